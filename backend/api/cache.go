@@ -1,117 +1,532 @@
 package api
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Cache stocke les coordonnées géographiques en mémoire et sur disque
-// Structure thread-safe pour éviter les problèmes de concurrence
-type Cache struct {
-	data map[string]GeocodeResponse // map[location]coordonnées
-	mu   sync.RWMutex               // mutex pour la sécurité thread
+// cacheFormatVersion est la version du format des caches enregistrés sur disque
+// Versions 0 et 1 : une simple map clé -> valeur, sans date ni entrée négative
+// Version 3 : coordonnées numériques dans le cache de coordonnées (voir GeocodeResponse.UnmarshalJSON)
+const cacheFormatVersion = 3
+
+// cacheRecordsVersion est la première version enregistrant une liste d'entrées avec leurs dates
+const cacheRecordsVersion = 2
+
+// CacheOptions configure un Cache
+type CacheOptions struct {
+	// TTL est la durée de vie d'une entrée (0 = sans expiration)
+	TTL time.Duration
+	// NegativeTTL est la durée pendant laquelle une recherche sans résultat est mémorisée
+	// (0 = pas de cache négatif)
+	NegativeTTL time.Duration
+	// MaxEntries est le nombre maximum d'entrées ; au-delà, les moins récemment utilisées
+	// sont supprimées (0 = illimité)
+	MaxEntries int
+	// NotFound est l'erreur qui signifie "aucun résultat" pour GetOrLoad :
+	// les chargements qui échouent avec cette erreur sont mis en cache négatif
+	NotFound error
+	// File est le fichier où le cache est enregistré ("" = cache uniquement en mémoire)
+	File string
 }
 
-var (
-	// Instance globale du cache
-	coordinatesCache *Cache
-	// Nom du fichier où sauvegarder le cache
-	cacheFileName = "coordinates_cache.json"
-)
+// CacheStats contient les compteurs d'utilisation d'un cache
+type CacheStats struct {
+	Entries     int        `json:"entries"`
+	Negative    int        `json:"negative"` // entrées négatives parmi Entries
+	Hits        int64      `json:"hits"`
+	Misses      int64      `json:"misses"`
+	HitRatio    float64    `json:"hitRatio"`              // Hits / (Hits + Misses), 0 sans aucune recherche
+	Evictions   int64      `json:"evictions"`             // entrées supprimées pour respecter MaxEntries
+	Expired     int64      `json:"expired"`               // entrées supprimées car trop anciennes
+	OldestEntry *time.Time `json:"oldestEntry,omitempty"` // date d'ajout de l'entrée la plus ancienne
+	FileSize    int64      `json:"fileSize,omitempty"`    // taille du fichier sur disque, en octets
+}
 
-// init initialise le cache au démarrage du programme
-func init() {
-	coordinatesCache = &Cache{
-		data: make(map[string]GeocodeResponse),
-	}
-	loadCacheFromDisk()
+// Cache est un cache clé -> valeur thread-safe, avec durée de vie des entrées, taille maximum
+// (éviction LRU), cache négatif et enregistrement optionnel sur disque
+type Cache[K comparable, V any] struct {
+	options CacheOptions
+
+	mu      sync.Mutex // protège entries, order et les compteurs
+	entries map[K]*list.Element
+	order   *list.List // entrées de la plus récemment utilisée (devant) à la moins récemment utilisée
+	stats   CacheStats
+
+	flights    flightGroup[V]
+	generation atomic.Uint64 // incrémenté à chaque modification, voir Generation
+	file       diskFile
+	writer     *debouncer
 }
 
-// GetFromCache récupère une coordonnée depuis le cache
-// Retourne les coordonnées et un booléen indiquant si elles ont été trouvées
-func GetFromCache(location string) (GeocodeResponse, bool) {
-	coordinatesCache.mu.RLock() // verrou en lecture
-	defer coordinatesCache.mu.RUnlock()
+// cacheItem est une entrée du cache
+type cacheItem[K comparable, V any] struct {
+	key       K
+	value     V
+	negative  bool      // true si la recherche n'a rien donné
+	storedAt  time.Time // date d'ajout
+	expiresAt time.Time // date d'expiration (zéro = jamais)
+}
 
-	response, found := coordinatesCache.data[location]
-	return response, found
+// CacheEntry est une entrée telle qu'enregistrée sur disque et renvoyée par Entries
+type CacheEntry[K comparable, V any] struct {
+	Key       K          `json:"key"`
+	Value     V          `json:"value"`
+	Negative  bool       `json:"negative,omitempty"`
+	StoredAt  time.Time  `json:"storedAt,omitzero"` // absente des fichiers écrits par WriteSorted
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
-// SaveToCache sauvegarde une coordonnée dans le cache en mémoire et sur disque
-func SaveToCache(location string, response GeocodeResponse) {
-	coordinatesCache.mu.Lock() // verrou en écriture
-	defer coordinatesCache.mu.Unlock()
+// NewCache crée un cache vide
+// Avec options.File, appeler Load pour relire le fichier, et Flush avant l'arrêt du programme
+func NewCache[K comparable, V any](options CacheOptions) *Cache[K, V] {
+	c := &Cache[K, V]{
+		options: options,
+		entries: make(map[K]*list.Element),
+		order:   list.New(),
+	}
+	if options.File != "" {
+		c.file = diskFile{path: options.File, version: cacheFormatVersion}
+		// Regroupe les sauvegardes rapprochées : une seule écriture pour une rafale de modifications
+		c.writer = &debouncer{delay: 2 * time.Second, write: c.save}
+	}
+	return c
+}
 
-	// Sauvegarder en mémoire
-	coordinatesCache.data[location] = response
+// Get retourne la valeur associée à key, si elle est présente, non expirée et non négative
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Sauvegarder sur disque de manière asynchrone pour ne pas bloquer
-	go saveCacheToDisk()
+	entry, found := c.lookup(key)
+	if !found || entry.negative {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
 }
 
-// loadCacheFromDisk charge le cache depuis le fichier JSON
-func loadCacheFromDisk() {
-	file, err := os.Open(cacheFileName)
-	if err != nil {
-		// Si le fichier n'existe pas, ce n'est pas une erreur grave
-		if os.IsNotExist(err) {
-			log.Printf("Fichier de cache non trouvé, création d'un nouveau cache")
-			return
+// GetOrLoad retourne la valeur associée à key, ou la charge avec load si elle est absente
+// Les chargements simultanés d'une même clé sont regroupés en un seul appel
+// Si load échoue avec options.NotFound, l'absence de résultat est mémorisée pendant NegativeTTL
+// et les appels suivants échouent avec la même erreur sans rappeler load
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	if value, found, err := c.cached(key); found {
+		return value, err
+	}
+
+	return c.flights.Do(ctx, fmt.Sprint(key), func(ctx context.Context) (V, error) {
+		// Un appel concurrent a pu remplir le cache entre-temps
+		if value, found, err := c.peek(key); found {
+			return value, err
 		}
-		log.Printf("Erreur lors de l'ouverture du fichier de cache: %v", err)
-		return
+
+		value, err := load(ctx)
+		if err != nil {
+			if c.options.NotFound != nil && errors.Is(err, c.options.NotFound) {
+				c.SetNegative(key)
+			}
+			return value, err
+		}
+		c.Set(key, value)
+		return value, nil
+	})
+}
+
+// cached cherche key et compte un succès ou un échec
+// found est true si la clé est en cache, y compris en négatif (err est alors non nulle)
+func (c *Cache[K, V]) cached(key K) (value V, found bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.lookup(key)
+	if !found {
+		return value, false, nil
+	}
+	if entry.negative {
+		return value, true, c.notFound(key)
 	}
-	defer file.Close()
+	return entry.value, true, nil
+}
 
-	var cacheData map[string]GeocodeResponse
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&cacheData)
-	if err != nil {
-		log.Printf("Erreur lors du décodage du cache: %v", err)
+// peek cherche key sans modifier les compteurs
+func (c *Cache[K, V]) peek(key K) (value V, found bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		return value, false, nil
+	}
+	entry := element.Value.(*cacheItem[K, V])
+	if entry.expired(time.Now()) {
+		return value, false, nil
+	}
+	if entry.negative {
+		return value, true, c.notFound(key)
+	}
+	return entry.value, true, nil
+}
+
+// notFound retourne l'erreur renvoyée pour une entrée négative
+func (c *Cache[K, V]) notFound(key K) error {
+	if c.options.NotFound == nil {
+		return fmt.Errorf("aucun résultat pour %v (en cache)", key)
+	}
+	return fmt.Errorf("%w: %v (en cache)", c.options.NotFound, key)
+}
+
+// lookup cherche key, supprime l'entrée si elle a expiré, et compte un succès ou un échec
+// Doit être appelée avec c.mu verrouillé
+func (c *Cache[K, V]) lookup(key K) (*cacheItem[K, V], bool) {
+	element, found := c.entries[key]
+	if !found {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheItem[K, V])
+	if entry.expired(time.Now()) {
+		c.remove(element)
+		c.stats.Expired++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	c.stats.Hits++
+	return entry, true
+}
+
+// ttl retourne la durée de vie d'une nouvelle entrée (0 = sans expiration)
+func (c *Cache[K, V]) ttl(negative bool) time.Duration {
+	if negative {
+		return c.options.NegativeTTL
+	}
+	return c.options.TTL
+}
+
+// Set ajoute ou remplace la valeur associée à key
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.store(&cacheItem[K, V]{key: key, value: value}, c.options.TTL)
+}
+
+// SetNegative mémorise que la recherche de key n'a rien donné
+// Sans effet si le cache négatif est désactivé (NegativeTTL nul)
+func (c *Cache[K, V]) SetNegative(key K) {
+	if c.options.NegativeTTL <= 0 {
 		return
 	}
+	c.store(&cacheItem[K, V]{key: key, negative: true}, c.options.NegativeTTL)
+}
+
+// store ajoute une entrée valable ttl (0 = sans expiration), en supprimant les plus anciennes si besoin
+func (c *Cache[K, V]) store(entry *cacheItem[K, V], ttl time.Duration) {
+	entry.storedAt = time.Now()
+	if ttl > 0 {
+		entry.expiresAt = entry.storedAt.Add(ttl)
+	}
+
+	c.mu.Lock()
+	c.insert(entry)
+	c.mu.Unlock()
+
+	c.changed()
+}
+
+// insert ajoute une entrée en tête de la liste LRU et applique MaxEntries
+// Doit être appelée avec c.mu verrouillé
+func (c *Cache[K, V]) insert(entry *cacheItem[K, V]) {
+	if element, found := c.entries[entry.key]; found {
+		c.remove(element)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+
+	for c.options.MaxEntries > 0 && c.order.Len() > c.options.MaxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove supprime une entrée
+// Doit être appelée avec c.mu verrouillé
+func (c *Cache[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheItem[K, V]).key)
+}
+
+// Delete supprime l'entrée associée à key et indique si elle existait
+func (c *Cache[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	element, found := c.entries[key]
+	if found {
+		c.remove(element)
+	}
+	c.mu.Unlock()
+
+	if found {
+		c.changed()
+	}
+	return found
+}
+
+// Clear supprime toutes les entrées (les compteurs sont conservés)
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	c.entries = make(map[K]*list.Element)
+	c.order.Init()
+	c.mu.Unlock()
+
+	c.changed()
+}
 
-	coordinatesCache.mu.Lock()
-	coordinatesCache.data = cacheData
-	coordinatesCache.mu.Unlock()
+// Len retourne le nombre d'entrées, y compris négatives
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats retourne les compteurs d'utilisation du cache
+func (c *Cache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*cacheItem[K, V])
+		if entry.negative {
+			stats.Negative++
+		}
+		if stats.OldestEntry == nil || entry.storedAt.Before(*stats.OldestEntry) {
+			storedAt := entry.storedAt
+			stats.OldestEntry = &storedAt
+		}
+	}
+	c.mu.Unlock()
 
-	log.Printf("Cache chargé avec %d entrées", len(cacheData))
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	if c.options.File != "" {
+		if info, err := os.Stat(c.options.File); err == nil {
+			stats.FileSize = info.Size()
+		}
+	}
+	return stats
 }
 
-// saveCacheToDisk sauvegarde le cache actuel dans le fichier JSON
-func saveCacheToDisk() {
-	coordinatesCache.mu.RLock()
-	// Créer une copie des données pour éviter de garder le verrou trop longtemps
-	dataCopy := make(map[string]GeocodeResponse)
-	for k, v := range coordinatesCache.data {
-		dataCopy[k] = v
+// Entries retourne les entrées non expirées, de la plus à la moins récemment utilisée
+// Ne modifie ni l'ordre LRU ni les compteurs
+func (c *Cache[K, V]) Entries() []CacheEntry[K, V] {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]CacheEntry[K, V], 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*cacheItem[K, V])
+		if !entry.expired(now) {
+			entries = append(entries, entry.export())
+		}
 	}
-	coordinatesCache.mu.RUnlock()
+	return entries
+}
+
+// Peek retourne l'entrée associée à key, y compris négative, sans modifier l'ordre LRU ni les compteurs
+func (c *Cache[K, V]) Peek(key K) (CacheEntry[K, V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[key]
+	if !found {
+		return CacheEntry[K, V]{}, false
+	}
+	entry := element.Value.(*cacheItem[K, V])
+	if entry.expired(time.Now()) {
+		return CacheEntry[K, V]{}, false
+	}
+	return entry.export(), true
+}
+
+// export convertit une entrée interne en CacheEntry
+func (e *cacheItem[K, V]) export() CacheEntry[K, V] {
+	exported := CacheEntry[K, V]{Key: e.key, Value: e.value, Negative: e.negative, StoredAt: e.storedAt}
+	if !e.expiresAt.IsZero() {
+		expiresAt := e.expiresAt
+		exported.ExpiresAt = &expiresAt
+	}
+	return exported
+}
+
+// expired indique si l'entrée a dépassé sa date d'expiration
+func (e *cacheItem[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// changed note une modification et programme l'enregistrement du cache sur disque, s'il est configuré
+func (c *Cache[K, V]) changed() {
+	c.generation.Add(1)
+	if c.writer != nil {
+		c.writer.Trigger()
+	}
+}
+
+// Generation retourne un compteur incrémenté à chaque ajout ou suppression d'entrée
+// Permet de savoir si des données calculées à partir du cache sont à refaire
+func (c *Cache[K, V]) Generation() uint64 {
+	return c.generation.Load()
+}
 
-	file, err := os.Create(cacheFileName)
+// Flush écrit immédiatement sur disque les modifications en attente
+// A appeler avant l'arrêt du programme
+func (c *Cache[K, V]) Flush() {
+	if c.writer != nil {
+		c.writer.Flush()
+	}
+}
+
+// WriteSorted écrit immédiatement le cache sur disque, les entrées triées par clé et sans leurs dates :
+// le même contenu donne toujours le même fichier, qui peut être livré ou versionné
+// Les entrées relues depuis ce fichier sont considérées comme ajoutées au chargement
+func (c *Cache[K, V]) WriteSorted(compare func(a, b K) int) error {
+	if c.writer == nil {
+		return nil
+	}
+	// Une écriture en attente, au format habituel, remplacerait ce fichier
+	c.writer.Flush()
+	c.writer.writing.Lock()
+	defer c.writer.writing.Unlock()
+
+	entries := c.Entries()
+	slices.SortFunc(entries, func(a, b CacheEntry[K, V]) int {
+		return compare(a.Key, b.Key)
+	})
+	for i := range entries {
+		entries[i].StoredAt = time.Time{}
+		entries[i].ExpiresAt = nil
+	}
+	return c.file.Write(entries)
+}
+
+// Load relit le cache depuis options.File et retourne le nombre d'entrées chargées
+// Un fichier absent n'est pas une erreur ; un fichier corrompu est remplacé par sa copie de secours
+// Les fichiers d'un ancien format sont relus puis réécrits au format actuel
+func (c *Cache[K, V]) Load() (int, error) {
+	if c.options.File == "" {
+		return 0, nil
+	}
+
+	var raw json.RawMessage
+	version, recovered, err := c.file.Read(&raw)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
 	if err != nil {
-		log.Printf("Erreur lors de la création du fichier de cache: %v", err)
-		return
+		return 0, err
+	}
+	if recovered != nil {
+		// Le fichier principal était illisible, mais la copie de secours a pu être chargée
+		log.Printf("Cache restauré depuis la copie de secours: %v", recovered)
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // Format JSON lisible
-	err = encoder.Encode(dataCopy)
+	records, err := c.decodeRecords(raw, version)
 	if err != nil {
-		log.Printf("Erreur lors de l'encodage du cache: %v", err)
-		return
+		return 0, fmt.Errorf("%s: %w", c.options.File, err)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	// Les enregistrements sont du plus au moins récemment utilisé : on les insère à l'envers
+	// pour retrouver le même ordre LRU
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		entry := &cacheItem[K, V]{key: record.Key, value: record.Value, negative: record.Negative, storedAt: record.StoredAt}
+		if record.ExpiresAt != nil {
+			entry.expiresAt = *record.ExpiresAt
+		}
+		if entry.storedAt.IsZero() {
+			// Fichier écrit par WriteSorted ou ancien format sans dates :
+			// la durée de vie (TTL ou NegativeTTL) compte à partir du chargement
+			entry.storedAt = now
+			if ttl := c.ttl(entry.negative); ttl > 0 {
+				entry.expiresAt = now.Add(ttl)
+			}
+		}
+		if entry.expired(now) {
+			continue
+		}
+		c.insert(entry)
 	}
+	loaded := c.order.Len()
+	c.mu.Unlock()
+	c.generation.Add(1)
+
+	if recovered != nil || version < cacheFormatVersion {
+		// Réécrire le fichier principal, au format actuel
+		c.changed()
+	}
+	return loaded, nil
 }
 
-// GetCacheStats retourne des statistiques sur le cache
-func GetCacheStats() map[string]int {
-	coordinatesCache.mu.RLock()
-	defer coordinatesCache.mu.RUnlock()
+// decodeRecords décode le contenu d'un fichier de cache selon sa version
+// Une valeur illisible n'empêche pas de relire les autres : elle est ignorée, ou devient une entrée
+// négative si son décodage échoue avec options.NotFound (ancienne absence de résultat)
+func (c *Cache[K, V]) decodeRecords(raw json.RawMessage, version int) ([]CacheEntry[K, V], error) {
+	var rawRecords []CacheEntry[K, json.RawMessage]
+	if version >= cacheRecordsVersion {
+		if err := json.Unmarshal(raw, &rawRecords); err != nil {
+			return nil, err
+		}
+	} else {
+		// Ancien format : une map sans dates, Load considère les entrées comme ajoutées au chargement
+		var legacy map[K]json.RawMessage
+		if err := json.Unmarshal(raw, &legacy); err != nil {
+			return nil, err
+		}
+		for key, value := range legacy {
+			rawRecords = append(rawRecords, CacheEntry[K, json.RawMessage]{Key: key, Value: value})
+		}
+	}
+
+	records := make([]CacheEntry[K, V], 0, len(rawRecords))
+	for _, rawRecord := range rawRecords {
+		record := CacheEntry[K, V]{
+			Key:       rawRecord.Key,
+			Negative:  rawRecord.Negative,
+			StoredAt:  rawRecord.StoredAt,
+			ExpiresAt: rawRecord.ExpiresAt,
+		}
+		if !record.Negative {
+			err := json.Unmarshal(rawRecord.Value, &record.Value)
+			switch {
+			case err == nil:
+			case c.options.NotFound != nil && errors.Is(err, c.options.NotFound):
+				// Absence de résultat de l'ancien format : entrée négative, soumise à NegativeTTL
+				record.Negative = true
+			default:
+				log.Printf("Entrée %v du cache %s ignorée: %v", record.Key, c.options.File, err)
+				continue
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
 
-	return map[string]int{
-		"entries": len(coordinatesCache.data),
+// save enregistre le cache dans options.File
+func (c *Cache[K, V]) save() {
+	// Entries copie les entrées : le verrou n'est pas gardé pendant l'écriture
+	if err := c.file.Write(c.Entries()); err != nil {
+		log.Printf("Erreur lors de l'écriture du cache %s: %v", c.options.File, err)
 	}
 }
@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"groupie-tracker/config"
 )
@@ -16,6 +19,133 @@ const (
 	BaseURL = "https://groupietrackers.herokuapp.com/api"
 )
 
+// Upstream est l'interface dont dépendent les handlers pour récupérer les données
+// Elle permet de brancher le backend sur l'API officielle, un miroir ou un faux serveur de test
+// Chaque méthode abandonne ses appels réseau quand ctx est annulé (client déconnecté, arrêt du serveur)
+type Upstream interface {
+	FetchArtists(ctx context.Context) ([]ArtistWithCustomImage, error)
+	FetchArtistConcerts(ctx context.Context, artistID int) (*ArtistConcerts, error)
+	FetchLocations(ctx context.Context, artistID int) (Relation, error)
+	FetchAllLocations(ctx context.Context) ([]Location, error)
+	FetchAllRelations(ctx context.Context) ([]Relation, error)
+}
+
+// Client interroge l'API Groupie Tracker à une adresse configurable
+type Client struct {
+	BaseURL    string       // adresse racine de l'API (sans "/" final)
+	HTTPClient *http.Client // client HTTP utilisé pour toutes les requêtes (timeouts, transport...)
+	UserAgent  string       // User-Agent envoyé à l'API
+	// MaxConcurrency limite le nombre de requêtes simultanées vers l'API (défaut: 4)
+	MaxConcurrency int
+
+	// Cache garde les réponses de l'API par chemin (nil = pas de cache)
+	// Les réponses 404 y sont mémorisées comme entrées négatives
+	Cache *Cache[string, []byte]
+
+	// inflight regroupe les requêtes identiques simultanées (même chemin) en une seule
+	inflight flightGroup[[]byte]
+}
+
+// ErrUpstreamNotFound est renvoyée quand l'API répond 404 pour la ressource demandée
+var ErrUpstreamNotFound = errors.New("ressource introuvable sur l'API")
+
+// NewClient crée un client pour l'API située à baseURL
+// Si httpClient est nil, un client avec un timeout par défaut est utilisé
+func NewClient(baseURL string, httpClient *http.Client, userAgent string) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: httpClient,
+		UserAgent:  userAgent,
+	}
+}
+
+// concurrency retourne le nombre maximum de requêtes simultanées vers l'API
+func (c *Client) concurrency() int {
+	if c.MaxConcurrency < 1 {
+		return 4
+	}
+	return c.MaxConcurrency
+}
+
+// NewClientFromConfig crée un client à partir des variables d'environnement
+func NewClientFromConfig() *Client {
+	client := NewClient(
+		config.GetUpstreamURL(),
+		&http.Client{Timeout: config.GetUpstreamTimeout()},
+		config.GetUserAgent(),
+	)
+	client.MaxConcurrency = config.GetFetchConcurrency()
+	if ttl := config.GetUpstreamCacheTTL(); ttl > 0 {
+		client.Cache = NewCache[string, []byte](CacheOptions{
+			TTL:         ttl,
+			NegativeTTL: config.GetUpstreamNegativeTTL(),
+			MaxEntries:  config.GetUpstreamCacheSize(),
+			NotFound:    ErrUpstreamNotFound,
+		})
+	}
+	return client
+}
+
+// getJSON fait une requête GET vers BaseURL + path et décode le JSON de la réponse dans target
+// La réponse vient du cache si elle y est, et les appels simultanés pour le même chemin
+// partagent une seule requête
+// La requête est abandonnée si ctx est annulé
+func (c *Client) getJSON(ctx context.Context, path string, target interface{}) error {
+	load := func(ctx context.Context) ([]byte, error) {
+		return c.get(ctx, path)
+	}
+	var body []byte
+	var err error
+	if c.Cache != nil {
+		body, err = c.Cache.GetOrLoad(ctx, path, load)
+	} else {
+		body, err = c.inflight.Do(ctx, path, load)
+	}
+	if err != nil {
+		return err
+	}
+
+	// json.Unmarshal convertit les bytes JSON en structs Go
+	// Utilise les tags json:"..." pour mapper les champs JSON aux champs de struct
+	return json.Unmarshal(body, target)
+}
+
+// get fait une requête GET vers BaseURL + path et retourne le corps de la réponse
+func (c *Client) get(ctx context.Context, path string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		request.Header.Set("User-Agent", c.UserAgent)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	// "defer" permet d'executer une fonction apres la fonction dans laquelle il est appelé
+	// En gros, on ferme la connection HTTP apres que la fonction get se termine
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("GET %s: %w", path, ErrUpstreamNotFound)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: statut inattendu %s", path, response.Status)
+	}
+
+	// Lit et récupère tout le contenu de la réponse HTTP (les données JSON brutes)
+	// ENTRÉE: response.Body = un "stream" de données (comme un tuyau d'eau qui coule)
+	// SORTIE: body = toutes les données JSON sous forme de []byte (tableau d'octets)
+	// POURQUOI: json.Unmarshal a besoin de TOUTES les données d'un coup, pas un stream
+	return io.ReadAll(response.Body)
+}
+
 // Representation de l'api en "struct" Go
 // Si tu vas sur l'url de l'api, tu verras que les données sont sous forme de json
 // la c'est la meme chose mais traduit en struct Go
@@ -45,39 +175,20 @@ type Relation struct {
 
 // FetchArtists récupère tous les artistes depuis l'API Groupie Tracker
 // Retourne une slice de structs Artist et une erreur si il y en a une (sinon "nil")
-func FetchArtists() ([]ArtistWithCustomImage, error) {
-	// Fait une requête HTTP GET vers l'adresse de base de l'api + /artists
-	response, err := http.Get(BaseURL + "/artists")
-	if err != nil {
-		// Si la requête réseau échoue, on log l'erreur et on la retourne
-		log.Printf("Error fetching artists: %v", err)
-		return nil, err
-	}
-	// "defer" permet d'executer une fonction apres la fonction dans laquelle il est appelé
-	// En gros, on ferme la connection HTTP apres que la fonction FetchArtists se termine
-	// Si tu comprends pas trop cette ligne, c'est pas hyper grave
-	defer response.Body.Close()
-
-	// Lit et récupère tout le contenu de la réponse HTTP (les données JSON brutes)
-	// ENTRÉE: response.Body = un "stream" de données (comme un tuyau d'eau qui coule)
-	// SORTIE: body = toutes les données JSON sous forme de []byte (tableau d'octets)
-	// POURQUOI: json.Unmarshal a besoin de TOUTES les données d'un coup, pas un stream
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		log.Printf("Error reading response body: %v", err)
-		return nil, err
-	}
-
+func (c *Client) FetchArtists(ctx context.Context) ([]ArtistWithCustomImage, error) {
 	// Crée une slice vide pour contenir nos artistes après la conversion JSON
 	var parsed []Artist
-	// json.Unmarshal convertit les bytes JSON en structs Go
-	// Utilise les tags json:"..." pour mapper les champs JSON aux champs de struct
-	err = json.Unmarshal(body, &parsed)
+	err := c.getJSON(ctx, "/artists", &parsed)
 	if err != nil {
-		log.Printf("Error unmarshaling JSON: %v", err)
+		log.Printf("Error fetching artists: %v", err)
 		return nil, err
 	}
 
+	return withCustomImages(parsed), nil
+}
+
+// withCustomImages ajoute à chaque artiste l'URL de son image servie par notre backend
+func withCustomImages(parsed []Artist) []ArtistWithCustomImage {
 	var artists []ArtistWithCustomImage
 
 	for _, artist := range parsed {
@@ -88,13 +199,7 @@ func FetchArtists() ([]ArtistWithCustomImage, error) {
 		artists = append(artists, newArtist)
 	}
 
-	return artists, nil
-}
-
-// Concert représente un concert avec sa date et ses lieux
-type Concert struct {
-	Date      string   `json:"date"`
-	Locations []string `json:"locations"`
+	return artists
 }
 
 // ArtistConcerts représente les concerts d'un artiste
@@ -103,72 +208,56 @@ type ArtistConcerts struct {
 	Concerts map[string][]string `json:"concerts"`
 }
 
-// FetchArtistConcerts récupère les concerts d'un artiste spécifique depuis l'API Groupie Tracker
-func FetchArtistConcerts(artistID int) (*ArtistConcerts, error) {
-	// Récupère les dates de concerts
-	datesResponse, err := http.Get(BaseURL + "/dates/" + fmt.Sprintf("%d", artistID))
-	if err != nil {
-		log.Printf("Error fetching dates: %v", err)
-		return nil, err
-	}
-	defer datesResponse.Body.Close()
-
-	// Récupère les lieux de concerts
-	locationsResponse, err := http.Get(BaseURL + "/locations/" + fmt.Sprintf("%d", artistID))
-	if err != nil {
-		log.Printf("Error fetching locations: %v", err)
-		return nil, err
-	}
-	defer locationsResponse.Body.Close()
-
-	// Parse les dates
-	var datesData struct {
-		ID    int      `json:"id"`
-		Dates []string `json:"dates"`
-	}
-
-	datesBody, err := io.ReadAll(datesResponse.Body)
-	if err != nil {
-		log.Printf("Error reading dates response: %v", err)
-		return nil, err
-	}
-
-	err = json.Unmarshal(datesBody, &datesData)
-	if err != nil {
-		log.Printf("Error unmarshaling dates JSON: %v", err)
-		return nil, err
-	}
-
-	// Parse les lieux
-	var locationsData struct {
-		ID        int      `json:"id"`
-		Locations []string `json:"locations"`
-	}
+// Dates représente les dates de concerts d'un artiste telles que renvoyées par /dates
+type Dates struct {
+	ID    int      `json:"id"`
+	Dates []string `json:"dates"`
+}
 
-	locationsBody, err := io.ReadAll(locationsResponse.Body)
+// FetchArtistConcerts récupère les concerts d'un artiste spécifique depuis l'API Groupie Tracker
+// Les dates et les lieux sont récupérés en parallèle
+func (c *Client) FetchArtistConcerts(ctx context.Context, artistID int) (*ArtistConcerts, error) {
+	var datesData Dates
+	var locationsData Location
+
+	err := Parallel(ctx, c.concurrency(),
+		// Récupère les dates de concerts
+		func(ctx context.Context) error {
+			err := c.getJSON(ctx, fmt.Sprintf("/dates/%d", artistID), &datesData)
+			if err != nil {
+				log.Printf("Error fetching dates: %v", err)
+			}
+			return err
+		},
+		// Récupère les lieux de concerts
+		func(ctx context.Context) error {
+			err := c.getJSON(ctx, fmt.Sprintf("/locations/%d", artistID), &locationsData)
+			if err != nil {
+				log.Printf("Error fetching locations: %v", err)
+			}
+			return err
+		},
+	)
 	if err != nil {
-		log.Printf("Error reading locations response: %v", err)
 		return nil, err
 	}
 
-	err = json.Unmarshal(locationsBody, &locationsData)
-	if err != nil {
-		log.Printf("Error unmarshaling locations JSON: %v", err)
-		return nil, err
-	}
+	return combineConcerts(artistID, datesData.Dates, locationsData.Locations), nil
+}
 
-	// Combine les dates et lieux
+// combineConcerts associe les dates et les lieux d'un artiste, dans l'ordre
+func combineConcerts(artistID int, dates []string, locations []string) *ArtistConcerts {
 	concerts := make(map[string][]string)
 
 	// Assure que nous avons le même nombre de dates et de lieux
-	minLen := len(datesData.Dates)
-	if len(locationsData.Locations) < minLen {
-		minLen = len(locationsData.Locations)
+	minLen := len(dates)
+	if len(locations) < minLen {
+		minLen = len(locations)
 	}
 
 	for i := 0; i < minLen; i++ {
-		date := datesData.Dates[i]
-		location := locationsData.Locations[i]
+		date := dates[i]
+		location := locations[i]
 
 		// Nettoyer la date (enlever l'astérisque si présente)
 		cleanDate := strings.TrimPrefix(date, "*")
@@ -182,35 +271,34 @@ func FetchArtistConcerts(artistID int) (*ArtistConcerts, error) {
 	return &ArtistConcerts{
 		ID:       artistID,
 		Concerts: concerts,
-	}, nil
+	}
 }
 
 type Relations struct {
 	Index []Relation `json:"index"`
 }
 
-func FetchLocations(artistID int) (Relation, error) {
-	response, err := http.Get(BaseURL + "/relation/" + fmt.Sprintf("%d", artistID))
+func (c *Client) FetchLocations(ctx context.Context, artistID int) (Relation, error) {
+	var relation Relation
+	err := c.getJSON(ctx, fmt.Sprintf("/relation/%d", artistID), &relation)
 	if err != nil {
 		log.Printf("Error fetching locations: %v", err)
 		return Relation{}, err
 	}
-	defer response.Body.Close()
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		log.Printf("Error reading response body: %v", err)
-		return Relation{}, err
-	}
+	return relation, nil
+}
 
-	var relation Relation
-	err = json.Unmarshal(body, &relation)
+// FetchAllRelations récupère les relations dates/lieux de tous les artistes
+func (c *Client) FetchAllRelations(ctx context.Context) ([]Relation, error) {
+	var relations Relations
+	err := c.getJSON(ctx, "/relation", &relations)
 	if err != nil {
-		log.Printf("Error unmarshaling locations JSON: %v", err)
-		return Relation{}, err
+		log.Printf("Error fetching all relations: %v", err)
+		return nil, err
 	}
 
-	return relation, nil
+	return relations.Index, nil
 }
 
 type Location struct {
@@ -218,26 +306,13 @@ type Location struct {
 	Locations []string `json:"locations"`
 }
 
-func FetchAllLocations() ([]Location, error) {
-	response, err := http.Get(BaseURL + "/locations")
-	if err != nil {
-		log.Printf("Error fetching all locations: %v", err)
-		return []Location{}, err
-	}
-	defer response.Body.Close()
-
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		log.Printf("Error reading response body: %v", err)
-		return []Location{}, err
-	}
-
+func (c *Client) FetchAllLocations(ctx context.Context) ([]Location, error) {
 	var locations struct {
 		Index []Location `json:"index"`
 	}
-	err = json.Unmarshal(body, &locations)
+	err := c.getJSON(ctx, "/locations", &locations)
 	if err != nil {
-		log.Printf("Error unmarshaling all locations JSON: %v", err)
+		log.Printf("Error fetching all locations: %v", err)
 		return []Location{}, err
 	}
 
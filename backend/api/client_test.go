@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestUpstream démarre un faux serveur d'API qui répond aux chemins de routes
+// et 404 à tous les autres
+func newTestUpstream(t *testing.T, routes map[string]string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, found := routes[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL+"/api/", server.Client(), "groupie-tracker-test")
+}
+
+func TestClientFetchArtistConcerts(t *testing.T) {
+	client := newTestUpstream(t, map[string]string{
+		"/api/dates/1":     `{"id":1,"dates":["*23-08-2019","24-08-2019"]}`,
+		"/api/locations/1": `{"id":1,"locations":["paris-france","lyon-france"]}`,
+	})
+
+	concerts, err := client.FetchArtistConcerts(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("FetchArtistConcerts: %v", err)
+	}
+	if concerts.ID != 1 || len(concerts.Concerts) != 2 {
+		t.Fatalf("FetchArtistConcerts = %+v, attendu 2 dates pour l'artiste 1", concerts)
+	}
+	if got := concerts.Concerts["23-08-2019"]; len(got) != 1 {
+		t.Errorf("concerts du 23-08-2019 = %v, attendu un seul lieu", got)
+	}
+}
+
+func TestClientNotFound(t *testing.T) {
+	client := newTestUpstream(t, map[string]string{})
+
+	_, err := client.FetchLocations(context.Background(), 999)
+	if !errors.Is(err, ErrUpstreamNotFound) {
+		t.Fatalf("FetchLocations sur une ressource absente: %v, attendu ErrUpstreamNotFound", err)
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// MaxZoom est le niveau de zoom maximum des cartes (tuiles OpenStreetMap)
+	MaxZoom = 20
+	// clusterCellPx est la taille en pixels à l'écran des cellules de regroupement :
+	// deux marqueurs à moins de ~60 px l'un de l'autre sont fusionnés
+	clusterCellPx = 60
+	// maxMercatorLat est la latitude limite de la projection Web Mercator
+	maxMercatorLat = 85.05112878
+	// maxClusterArtists est le nombre d'artistes donnés en exemple pour un groupe
+	maxClusterArtists = 5
+)
+
+// Cluster est un groupe de lieux de concerts proches à un niveau de zoom donné
+type Cluster struct {
+	Count       int         `json:"count"`          // nombre de concerts
+	Locations   int         `json:"locations"`      // nombre de lieux
+	Slug        string      `json:"slug,omitempty"` // le lieu, si le groupe n'en contient qu'un
+	Centroid    GeoPoint    `json:"centroid"`       // barycentre des lieux, pondéré par leur nombre de concerts
+	BoundingBox BoundingBox `json:"boundingBox"`    // rectangle englobant les lieux du groupe
+	Artists     []string    `json:"artists"`        // quelques artistes, ceux qui y ont le plus joué d'abord
+	ArtistCount int         `json:"artistCount"`    // nombre total d'artistes
+}
+
+// ClusterLocations regroupe les lieux proches à l'écran au niveau de zoom donné, sur une grille
+// en coordonnées Web Mercator (celles de Leaflet et des tuiles OpenStreetMap)
+// Chaque lieu compte pour ses concerts ; les lieux sans concert sont ignorés
+// Les groupes sont triés du plus gros au plus petit
+func ClusterLocations(locations []IndexedLocation, zoom int) []Cluster {
+	type cell struct{ x, y int }
+	worldPx := 256 * math.Exp2(float64(zoom))
+
+	members := make(map[cell][]IndexedLocation)
+	for _, location := range locations {
+		if len(location.Concerts) == 0 {
+			continue
+		}
+		x, y := mercatorPixel(location.Point, worldPx)
+		key := cell{x: int(x / clusterCellPx), y: int(y / clusterCellPx)}
+		members[key] = append(members[key], location)
+	}
+
+	clusters := make([]Cluster, 0, len(members))
+	for _, group := range members {
+		clusters = append(clusters, newCluster(group))
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		if clusters[i].Centroid.Lat != clusters[j].Centroid.Lat {
+			return clusters[i].Centroid.Lat > clusters[j].Centroid.Lat
+		}
+		return clusters[i].Centroid.Lon < clusters[j].Centroid.Lon
+	})
+	return clusters
+}
+
+// newCluster calcule le groupe formé par des lieux d'une même cellule
+func newCluster(group []IndexedLocation) Cluster {
+	cluster := Cluster{
+		Locations:   len(group),
+		BoundingBox: PointBox(group[0].Point.Lat, group[0].Point.Lon),
+	}
+	if len(group) == 1 {
+		cluster.Slug = group[0].Slug
+	}
+
+	var latSum, lonSum float64
+	concertsByArtist := make(map[string]int)
+	for _, location := range group {
+		weight := float64(len(location.Concerts))
+		cluster.Count += len(location.Concerts)
+		latSum += location.Point.Lat * weight
+		lonSum += location.Point.Lon * weight
+
+		cluster.BoundingBox.South = math.Min(cluster.BoundingBox.South, location.Point.Lat)
+		cluster.BoundingBox.North = math.Max(cluster.BoundingBox.North, location.Point.Lat)
+		cluster.BoundingBox.West = math.Min(cluster.BoundingBox.West, location.Point.Lon)
+		cluster.BoundingBox.East = math.Max(cluster.BoundingBox.East, location.Point.Lon)
+
+		for _, concert := range location.Concerts {
+			concertsByArtist[concert.ArtistName]++
+		}
+	}
+	// Une cellule ne traverse jamais l'antiméridien : la moyenne des longitudes a un sens
+	cluster.Centroid = GeoPoint{Lat: latSum / float64(cluster.Count), Lon: lonSum / float64(cluster.Count)}
+
+	artists := make([]string, 0, len(concertsByArtist))
+	for artist := range concertsByArtist {
+		artists = append(artists, artist)
+	}
+	sort.Slice(artists, func(i, j int) bool {
+		if concertsByArtist[artists[i]] != concertsByArtist[artists[j]] {
+			return concertsByArtist[artists[i]] > concertsByArtist[artists[j]]
+		}
+		return artists[i] < artists[j]
+	})
+	cluster.ArtistCount = len(artists)
+	cluster.Artists = artists[:min(len(artists), maxClusterArtists)]
+	return cluster
+}
+
+// mercatorPixel projette un point en pixels Web Mercator, pour un monde large de worldPx pixels
+func mercatorPixel(point GeoPoint, worldPx float64) (x, y float64) {
+	lat := math.Max(-maxMercatorLat, math.Min(maxMercatorLat, point.Lat)) * math.Pi / 180
+	x = (point.Lon + 180) / 360 * worldPx
+	y = (1 - math.Log(math.Tan(lat)+1/math.Cos(lat))/math.Pi) / 2 * worldPx
+	// Les points sur le bord est ou sud tombent dans la dernière cellule, pas dans une cellule en dehors
+	return math.Min(x, worldPx-1), math.Min(y, worldPx-1)
+}
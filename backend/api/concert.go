@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout est le format des dates de concerts de l'API, ex: "23-08-2019"
+const DateLayout = "02-01-2006"
+
+// GeoPoint est une position géographique en degrés décimaux
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Concert représente un concert : un artiste, une date et un lieu
+type Concert struct {
+	ArtistID    int       `json:"artistId"`
+	ArtistName  string    `json:"artistName"`
+	Date        time.Time `json:"date"`
+	City        string    `json:"city,omitempty"`   // vide si le lieu est une région (ex: "north_carolina-usa")
+	Region      string    `json:"region,omitempty"` // état, province ou région quand le lieu n'est pas une ville
+	Country     string    `json:"country"`
+	CountryCode string    `json:"countryCode,omitempty"` // code ISO 3166-1 alpha-2, ex: "US"
+	Slug        string    `json:"slug"`                  // lieu brut de l'API, ex: "north_carolina-usa"
+	Point       *GeoPoint `json:"point,omitempty"`       // coordonnées, si le lieu est déjà géocodé
+}
+
+// ParseConcertDate convertit une date de l'API ("*23-08-2019" ou "23-08-2019") en time.Time
+func ParseConcertDate(raw string) (time.Time, error) {
+	return time.Parse(DateLayout, strings.TrimPrefix(strings.TrimSpace(raw), "*"))
+}
+
+// NewConcert construit un concert à partir d'une date et d'un slug de lieu de l'API
+// Les coordonnées sont prises dans le cache de géocodage, sans appel réseau
+func NewConcert(artist Artist, rawDate string, slug string) (Concert, error) {
+	date, err := ParseConcertDate(rawDate)
+	if err != nil {
+		return Concert{}, fmt.Errorf("date de concert invalide %q: %w", rawDate, err)
+	}
+	city, country, err := SplitLocation(slug)
+	if err != nil {
+		return Concert{}, err
+	}
+
+	concert := Concert{
+		ArtistID:   artist.ID,
+		ArtistName: artist.Name,
+		Date:       date,
+		City:       formatPlace(city),
+		Country:    formatCountry(country),
+		Slug:       slug,
+	}
+	concert.CountryCode, _ = CountryCode(country)
+
+	if coordinates, found := GetFromCache(slug); found {
+		if isRegion(coordinates.Type) {
+			concert.Region = concert.City
+			concert.City = ""
+		}
+		concert.Point = &GeoPoint{Lat: coordinates.Lat, Lon: coordinates.Lon}
+	}
+
+	return concert, nil
+}
+
+// BuildConcerts construit la liste des concerts de tous les artistes à partir des relations,
+// triée par ordre chronologique
+// Les dates illisibles de l'API sont ignorées
+func BuildConcerts(artists []ArtistWithCustomImage, relations []Relation) []Concert {
+	artistsByID := make(map[int]Artist, len(artists))
+	for _, artist := range artists {
+		artistsByID[artist.ID] = artist.Artist
+	}
+
+	concerts := []Concert{}
+	for _, relation := range relations {
+		artist, found := artistsByID[relation.ID]
+		if !found {
+			continue
+		}
+		for slug, dates := range relation.DatesLocations {
+			for _, rawDate := range dates {
+				concert, err := NewConcert(artist, rawDate, slug)
+				if err != nil {
+					continue
+				}
+				concerts = append(concerts, concert)
+			}
+		}
+	}
+
+	SortConcerts(concerts)
+	return concerts
+}
+
+// SortConcerts trie les concerts par date, puis par artiste et par lieu
+func SortConcerts(concerts []Concert) {
+	sort.Slice(concerts, func(i, j int) bool {
+		if !concerts[i].Date.Equal(concerts[j].Date) {
+			return concerts[i].Date.Before(concerts[j].Date)
+		}
+		if concerts[i].ArtistID != concerts[j].ArtistID {
+			return concerts[i].ArtistID < concerts[j].ArtistID
+		}
+		return concerts[i].Slug < concerts[j].Slug
+	})
+}
+
+// isRegion indique si un type de lieu Nominatim désigne une région plutôt qu'une ville
+func isRegion(placeType string) bool {
+	switch placeType {
+	case "state", "province", "region", "county", "state_district":
+		return true
+	}
+	return false
+}
+
+// formatPlace rend lisible une partie de slug, ex: "north_carolina" -> "North Carolina"
+func formatPlace(part string) string {
+	return strings.Title(strings.ReplaceAll(part, "_", " "))
+}
+
+// formatCountry rend lisible un pays de slug, ex: "new_zealand" -> "New Zealand", "usa" -> "USA"
+func formatCountry(country string) string {
+	if len(country) <= 3 {
+		// Abréviations de pays du jeu de données (usa, uk)
+		return strings.ToUpper(country)
+	}
+	return formatPlace(country)
+}
@@ -1,71 +1,190 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io"
+	"errors"
 	"log"
-	"net/http"
 	"strings"
+
+	"groupie-tracker/config"
 )
 
-// GeocodeResponse représente la réponse de l'API Nominatim d'OpenStreetMap
-type GeocodeResponse [1]struct {
-	PlaceID     int       `json:"place_id"`
-	Licence     string    `json:"licence"`
-	OsmType     string    `json:"osm_type"`
-	OsmID       int       `json:"osm_id"`
-	BoundingBox [4]string `json:"boundingbox"`
-	Lat         string    `json:"lat"`
-	Lon         string    `json:"lon"`
-	Name        string    `json:"name"`
-	DisplayName string    `json:"display_name"`
-	Category    string    `json:"category"`
-	Type        string    `json:"addresstype"`
-	Importance  float64   `json:"importance"`
+// GeocodeResponse contient les coordonnées d'un lieu géocodé
+// Les coordonnées sont converties en nombres et vérifiées une seule fois, au géocodage
+type GeocodeResponse struct {
+	Name        string      `json:"name"`
+	DisplayName string      `json:"displayName"`
+	Lat         float64     `json:"lat"`
+	Lon         float64     `json:"lon"`
+	BoundingBox BoundingBox `json:"boundingBox"`
+	Category    string      `json:"category,omitempty"`
+	Type        string      `json:"type,omitempty"`        // type de lieu Nominatim, ex: "city", "state"
+	CountryCode string      `json:"countryCode,omitempty"` // code ISO 3166-1 alpha-2, ex: "FR"
+	Importance  float64     `json:"importance,omitempty"`
+	OsmType     string      `json:"osmType,omitempty"`
+	OsmID       int64       `json:"osmId,omitempty"`
+	Licence     string      `json:"licence,omitempty"` // licence des données OpenStreetMap
 }
 
-// GetCoordinates récupère les coordonnées géographiques d'un lieu donné
-// Utilise l'API Nominatim d'OpenStreetMap pour convertir "ville-pays" en latitude/longitude
-// Vérifie d'abord le cache avant de faire un appel API
-func GetCoordinates(location string) (GeocodeResponse, error) {
-	// Vérifier le cache d'abord
-	if cachedResponse, found := GetFromCache(location); found {
-		return cachedResponse, nil
-	}
+// Validate vérifie que les coordonnées et la bounding box sont valides
+func (g GeocodeResponse) Validate() error {
+	return validateCoordinates(g.Lat, g.Lon, g.BoundingBox)
+}
 
-	// Divise la location en ville et pays (format: "ville-pays")
-	str := strings.Split(location, "-")
-	if len(str) < 2 {
-		return GeocodeResponse{}, fmt.Errorf("format de location invalide: %s", location)
+// UnmarshalJSON décode des coordonnées numériques, ou l'ancien format textuel de Nominatim
+// ([{"lat": "48.85", ...}]) écrit par les versions précédentes du cache
+func (g *GeocodeResponse) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var places []nominatimPlace
+		if err := json.Unmarshal(trimmed, &places); err != nil {
+			return err
+		}
+		if len(places) == 0 || places[0].Lat == "" {
+			// Les anciennes versions mettaient aussi en cache les recherches sans résultat
+			return ErrNoResult
+		}
+		response, err := places[0].response()
+		if err != nil {
+			return err
+		}
+		*g = response
+		return nil
 	}
 
-	city := str[0]
-	country := str[1]
+	// geocodeResponse n'a pas de méthode UnmarshalJSON : pas d'appel récursif
+	type geocodeResponse GeocodeResponse
+	return json.Unmarshal(data, (*geocodeResponse)(g))
+}
+
+// geocoder est partagé par tout le processus, pour que la limite d'une requête par seconde
+// de Nominatim soit globale
+var geocoder Geocoder = NewNominatimClientFromConfig()
+
+// coordinatesCache garde les coordonnées des lieux déjà géocodés, en mémoire et dans coordinates_cache.json
+// Les lieux sans résultat sont aussi mémorisés, pour ne pas réinterroger le géocodeur à chaque requête
+var coordinatesCache = NewCache[string, GeocodeResponse](CacheOptions{
+	TTL:         config.GetGeocodeCacheTTL(),
+	NegativeTTL: config.GetGeocodeNegativeTTL(),
+	MaxEntries:  config.GetGeocodeCacheSize(),
+	NotFound:    ErrNoResult,
+	File:        "coordinates_cache.json",
+})
 
-	// Appel à l'API Nominatim
-	response, err := http.Get(fmt.Sprintf("https://nominatim.openstreetmap.org/search?q=%s,%s&format=jsonv2&accept-language=fr", city, country))
+// init charge le cache de coordonnées au démarrage du programme
+func init() {
+	loaded, err := coordinatesCache.Load()
 	if err != nil {
-		log.Printf("Erreur lors de la récupération des coordonnées: %v", err)
-		return GeocodeResponse{}, err
+		log.Printf("Erreur lors du chargement du cache: %v", err)
+		return
 	}
-	defer response.Body.Close()
+	log.Printf("Cache chargé avec %d entrées", loaded)
+}
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		log.Printf("Erreur lors de la lecture de la réponse: %v", err)
-		return GeocodeResponse{}, err
+// GetFromCache récupère les coordonnées d'un lieu depuis les corrections manuelles ou le cache,
+// sans appel au géocodeur
+// Ne compte ni succès ni échec et ne modifie pas l'ordre LRU : appelée pour chaque concert de
+// /concerts et des exports, elle fausserait sinon les statistiques du cache
+// Retourne les coordonnées et un booléen indiquant si elles ont été trouvées
+func GetFromCache(location string) (GeocodeResponse, bool) {
+	if response, found := overrideResponse(location); found {
+		return response, true
+	}
+	entry, found := coordinatesCache.Peek(location)
+	if !found || entry.Negative {
+		return GeocodeResponse{}, false
 	}
+	return entry.Value, true
+}
+
+// CoordinatesGeneration retourne un compteur qui change dès qu'un lieu est géocodé, invalidé
+// ou corrigé à la main : les index construits à partir des coordonnées sont alors à refaire
+func CoordinatesGeneration() uint64 {
+	return coordinatesCache.Generation() + overrides.generation.Load()
+}
+
+// SaveToCache enregistre les coordonnées d'un lieu dans le cache
+func SaveToCache(location string, response GeocodeResponse) {
+	coordinatesCache.Set(location, response)
+}
+
+// FlushCache écrit immédiatement sur disque les modifications du cache en attente
+// A appeler avant l'arrêt du programme
+func FlushCache() {
+	coordinatesCache.Flush()
+}
+
+// WriteSortedCache écrit immédiatement le cache de coordonnées sur disque, trié par lieu et sans dates,
+// pour que deux pré-remplissages identiques produisent le même fichier
+func WriteSortedCache() error {
+	return coordinatesCache.WriteSorted(strings.Compare)
+}
+
+// GetCacheStats retourne les compteurs d'utilisation du cache de coordonnées
+func GetCacheStats() CacheStats {
+	return coordinatesCache.Stats()
+}
+
+// CacheEntries retourne les lieux du cache de coordonnées, du plus au moins récemment utilisé
+func CacheEntries() []CacheEntry[string, GeocodeResponse] {
+	return coordinatesCache.Entries()
+}
+
+// GetCacheEntry retourne l'entrée du cache de coordonnées d'un lieu, y compris un lieu sans résultat
+func GetCacheEntry(location string) (CacheEntry[string, GeocodeResponse], bool) {
+	return coordinatesCache.Peek(location)
+}
+
+// InvalidateCache supprime un lieu du cache de coordonnées : il sera re-géocodé à la prochaine demande
+// Indique si le lieu était en cache
+func InvalidateCache(location string) bool {
+	return coordinatesCache.Delete(location)
+}
+
+// ClearCache vide le cache de coordonnées
+func ClearCache() {
+	coordinatesCache.Clear()
+}
 
-	var geocodeResponse GeocodeResponse
-	err = json.Unmarshal(body, &geocodeResponse)
+// Regeocode géocode à nouveau un lieu, sans tenir compte du cache, et met le cache à jour
+// En cas d'erreur autre que ErrNoResult, les coordonnées déjà en cache sont conservées
+func Regeocode(ctx context.Context, location string) (GeocodeResponse, error) {
+	geocodeResponse, err := geocoder.Geocode(ctx, location)
+	if errors.Is(err, ErrNoResult) {
+		coordinatesCache.Delete(location)
+		coordinatesCache.SetNegative(location)
+		return GeocodeResponse{}, err
+	}
 	if err != nil {
-		log.Printf("Erreur lors du parsing JSON: %v", err)
 		return GeocodeResponse{}, err
 	}
+	coordinatesCache.Set(location, geocodeResponse)
+	return geocodeResponse, nil
+}
 
-	// Sauvegarder dans le cache pour les prochaines fois
-	SaveToCache(location, geocodeResponse)
+// SetGeocoder remplace le géocodeur utilisé pour les lieux absents du cache
+// A appeler au démarrage, avant de servir des requêtes
+func SetGeocoder(g Geocoder) {
+	geocoder = g
+}
 
-	return geocodeResponse, nil
+// GetCoordinates récupère les coordonnées géographiques d'un lieu donné
+// Convertit "ville-pays" en latitude/longitude via le géocodeur configuré (Nominatim par défaut)
+// Vérifie d'abord le cache avant de faire un appel au géocodeur, y compris pour les lieux déjà connus sans résultat
+// L'appel au géocodeur est abandonné si ctx est annulé
+func GetCoordinates(ctx context.Context, location string) (GeocodeResponse, error) {
+	// Une correction manuelle est prioritaire sur le cache et le géocodeur
+	if response, found := overrideResponse(location); found {
+		return response, nil
+	}
+
+	return coordinatesCache.GetOrLoad(ctx, location, func(ctx context.Context) (GeocodeResponse, error) {
+		// ErrGeocoderUnavailable n'est pas options.NotFound : le lieu n'est pas mis en cache négatif
+		geocodeResponse, err := geocoder.Geocode(ctx, location)
+		if err != nil && !errors.Is(err, ErrGeocoderUnavailable) {
+			log.Printf("Erreur lors du géocodage de %s: %v", location, err)
+		}
+		return geocodeResponse, err
+	})
 }
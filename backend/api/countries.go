@@ -0,0 +1,168 @@
+package api
+
+import "strings"
+
+// countryCodes associe les pays tels qu'écrits dans les slugs du jeu de données ("ville-pays")
+// à leur code ISO 3166-1 alpha-2
+var countryCodes = map[string]string{
+	"argentina":            "AR",
+	"australia":            "AU",
+	"austria":              "AT",
+	"belarus":              "BY",
+	"belgium":              "BE",
+	"brazil":               "BR",
+	"canada":               "CA",
+	"chile":                "CL",
+	"china":                "CN",
+	"colombia":             "CO",
+	"costa_rica":           "CR",
+	"croatia":              "HR",
+	"czech_republic":       "CZ",
+	"czechia":              "CZ",
+	"denmark":              "DK",
+	"estonia":              "EE",
+	"finland":              "FI",
+	"france":               "FR",
+	"french_polynesia":     "PF",
+	"germany":              "DE",
+	"greece":               "GR",
+	"hungary":              "HU",
+	"iceland":              "IS",
+	"india":                "IN",
+	"indonesia":            "ID",
+	"ireland":              "IE",
+	"israel":               "IL",
+	"italy":                "IT",
+	"japan":                "JP",
+	"latvia":               "LV",
+	"lithuania":            "LT",
+	"luxembourg":           "LU",
+	"malaysia":             "MY",
+	"mexico":               "MX",
+	"netherlands":          "NL",
+	"netherlands_antilles": "AN",
+	"new_caledonia":        "NC",
+	"new_zealand":          "NZ",
+	"norway":               "NO",
+	"peru":                 "PE",
+	"philippines":          "PH",
+	"poland":               "PL",
+	"portugal":             "PT",
+	"qatar":                "QA",
+	"romania":              "RO",
+	"russia":               "RU",
+	"saudi_arabia":         "SA",
+	"serbia":               "RS",
+	"singapore":            "SG",
+	"slovakia":             "SK",
+	"slovenia":             "SI",
+	"south_africa":         "ZA",
+	"south_korea":          "KR",
+	"spain":                "ES",
+	"sweden":               "SE",
+	"switzerland":          "CH",
+	"taiwan":               "TW",
+	"thailand":             "TH",
+	"turkey":               "TR",
+	"uk":                   "GB",
+	"ukraine":              "UA",
+	"united_arab_emirates": "AE",
+	"usa":                  "US",
+}
+
+// CountryCode retourne le code ISO 3166-1 alpha-2 d'un pays du jeu de données, ex: "usa" -> "US"
+func CountryCode(country string) (string, bool) {
+	code, found := countryCodes[country]
+	return code, found
+}
+
+// countryNames associe les noms de pays tels qu'écrits par Nominatim en français (dernière partie
+// de display_name, sans accents ni majuscules, voir Fold) à leur code ISO 3166-1 alpha-2
+// Sert aux lieux géocodés sans addressdetails, dont le cache ne contient pas le code pays
+var countryNames = map[string]string{
+	"afrique du sud":         "ZA",
+	"allemagne":              "DE",
+	"antilles neerlandaises": "AN",
+	"arabie saoudite":        "SA",
+	"argentine":              "AR",
+	"australie":              "AU",
+	"autriche":               "AT",
+	"belarus":                "BY",
+	"belgique":               "BE",
+	"bielorussie":            "BY",
+	"bresil":                 "BR",
+	"canada":                 "CA",
+	"chili":                  "CL",
+	"chine":                  "CN",
+	"colombie":               "CO",
+	"coree du sud":           "KR",
+	"costa rica":             "CR",
+	"croatie":                "HR",
+	"danemark":               "DK",
+	"emirats arabes unis":    "AE",
+	"espagne":                "ES",
+	"estonie":                "EE",
+	"etats-unis":             "US",
+	"etats-unis d'amerique":  "US",
+	"finlande":               "FI",
+	"france":                 "FR",
+	"grece":                  "GR",
+	"hongrie":                "HU",
+	"inde":                   "IN",
+	"indonesie":              "ID",
+	"irlande":                "IE",
+	"islande":                "IS",
+	"israel":                 "IL",
+	"italie":                 "IT",
+	"japon":                  "JP",
+	"lettonie":               "LV",
+	"lituanie":               "LT",
+	"luxembourg":             "LU",
+	"malaisie":               "MY",
+	"mexique":                "MX",
+	"norvege":                "NO",
+	"nouvelle-caledonie":     "NC",
+	"nouvelle-zelande":       "NZ",
+	"pays-bas":               "NL",
+	"perou":                  "PE",
+	"philippines":            "PH",
+	"pologne":                "PL",
+	"polynesie francaise":    "PF",
+	"portugal":               "PT",
+	"qatar":                  "QA",
+	"roumanie":               "RO",
+	"royaume-uni":            "GB",
+	"russie":                 "RU",
+	"serbie":                 "RS",
+	"singapour":              "SG",
+	"slovaquie":              "SK",
+	"slovenie":               "SI",
+	"suede":                  "SE",
+	"suisse":                 "CH",
+	"taiwan":                 "TW",
+	"tchequie":               "CZ",
+	"thailande":              "TH",
+	"turquie":                "TR",
+	"ukraine":                "UA",
+}
+
+// CountryCodeFromDisplayName retourne le code ISO du pays d'un display_name Nominatim en français,
+// ex: "Aalborg, Commune d'Aalborg, Jutland du Nord, 9000, Danemark" -> "DK"
+func CountryCodeFromDisplayName(displayName string) (string, bool) {
+	parts := strings.Split(displayName, ",")
+	code, found := countryNames[Fold(strings.TrimSpace(parts[len(parts)-1]))]
+	return code, found
+}
+
+// sovereignCountries associe les territoires du jeu de données au pays que Nominatim
+// donne parfois à leur place, ex: "France" pour un lieu de Polynésie française
+var sovereignCountries = map[string]string{
+	"AN": "NL",
+	"NC": "FR",
+	"PF": "FR",
+}
+
+// SameCountry indique si le pays géocodé correspond au pays attendu, ou à son pays de rattachement
+func SameCountry(expected, geocoded string) bool {
+	return geocoded == expected || geocoded == sovereignCountries[expected]
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoSnapshot est renvoyée tant qu'aucun jeu de données n'a pu être chargé
+	ErrNoSnapshot = errors.New("jeu de données pas encore chargé")
+	// ErrArtistNotFound est renvoyée quand l'ID demandé n'existe pas dans le jeu de données
+	ErrArtistNotFound = errors.New("artiste introuvable")
+)
+
+// Dataset est une copie complète des données de l'API Groupie Tracker à un instant donné
+type Dataset struct {
+	Artists   []ArtistWithCustomImage `json:"artists"`
+	Locations []Location              `json:"locations"`
+	Dates     []Dates                 `json:"dates"`
+	Relations []Relation              `json:"relations"`
+	FetchedAt time.Time               `json:"fetchedAt"`
+
+	// Index par ID d'artiste, reconstruits à chaque chargement
+	locationsByID map[int]int
+	datesByID     map[int]int
+	relationsByID map[int]int
+}
+
+// DatasetSource est une source capable de fournir le jeu de données complet
+type DatasetSource interface {
+	FetchDataset(ctx context.Context) (*Dataset, error)
+}
+
+// FetchDataset récupère artistes, lieux, dates et relations depuis l'API, en parallèle
+func (c *Client) FetchDataset(ctx context.Context) (*Dataset, error) {
+	var dataset Dataset
+
+	err := Parallel(ctx, c.concurrency(),
+		func(ctx context.Context) (err error) {
+			dataset.Artists, err = c.FetchArtists(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataset.Locations, err = c.FetchAllLocations(ctx)
+			return err
+		},
+		func(ctx context.Context) error {
+			var dates struct {
+				Index []Dates `json:"index"`
+			}
+			err := c.getJSON(ctx, "/dates", &dates)
+			if err != nil {
+				log.Printf("Error fetching all dates: %v", err)
+				return err
+			}
+			dataset.Dates = dates.Index
+			return nil
+		},
+		func(ctx context.Context) (err error) {
+			dataset.Relations, err = c.FetchAllRelations(ctx)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset.FetchedAt = time.Now()
+	return &dataset, nil
+}
+
+// index construit les index par ID d'artiste
+func (d *Dataset) index() {
+	d.locationsByID = make(map[int]int, len(d.Locations))
+	for i, location := range d.Locations {
+		d.locationsByID[location.ID] = i
+	}
+	d.datesByID = make(map[int]int, len(d.Dates))
+	for i, dates := range d.Dates {
+		d.datesByID[dates.ID] = i
+	}
+	d.relationsByID = make(map[int]int, len(d.Relations))
+	for i, relation := range d.Relations {
+		d.relationsByID[relation.ID] = i
+	}
+}
+
+// Store garde en mémoire le dernier jeu de données valide et le rafraîchit en arrière-plan
+// Il implémente Upstream : les handlers sont servis depuis la mémoire, sans appel réseau
+type Store struct {
+	source   DatasetSource
+	interval time.Duration
+
+	mu          sync.RWMutex
+	snapshot    *Dataset
+	lastError   error
+	lastAttempt time.Time
+	listeners   []func(*Dataset)
+}
+
+// NewStore crée un store alimenté par source et rafraîchi toutes les interval
+func NewStore(source DatasetSource, interval time.Duration) *Store {
+	return &Store{
+		source:   source,
+		interval: interval,
+	}
+}
+
+// Source retourne la source du jeu de données
+func (s *Store) Source() DatasetSource {
+	return s.source
+}
+
+// Refresh recharge le jeu de données depuis la source
+// En cas d'échec, le dernier jeu de données valide reste servi
+func (s *Store) Refresh(ctx context.Context) error {
+	dataset, err := s.source.FetchDataset(ctx)
+
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	s.lastError = err
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	dataset.index()
+	s.snapshot = dataset
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	// Prévenir les abonnés en dehors du verrou, ils peuvent relire le store
+	for _, listener := range listeners {
+		listener(dataset)
+	}
+	return nil
+}
+
+// OnRefresh enregistre une fonction appelée à chaque nouveau jeu de données chargé
+// Si un jeu de données est déjà chargé, la fonction est appelée immédiatement avec celui-ci
+func (s *Store) OnRefresh(listener func(*Dataset)) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, listener)
+	snapshot := s.snapshot
+	s.mu.Unlock()
+
+	if snapshot != nil {
+		listener(snapshot)
+	}
+}
+
+// Run rafraîchit le jeu de données à intervalle régulier jusqu'à l'annulation de ctx
+func (s *Store) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("Échec du rafraîchissement du jeu de données, conservation de l'ancien: %v", err)
+				continue
+			}
+			log.Printf("Jeu de données rafraîchi")
+		}
+	}
+}
+
+// Snapshot retourne le jeu de données courant, ou ErrNoSnapshot s'il n'a jamais été chargé
+// Le jeu de données retourné est partagé et ne doit pas être modifié
+func (s *Store) Snapshot() (*Dataset, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.snapshot == nil {
+		if s.lastError != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNoSnapshot, s.lastError)
+		}
+		return nil, ErrNoSnapshot
+	}
+	return s.snapshot, nil
+}
+
+// FetchArtists retourne les artistes du jeu de données courant
+func (s *Store) FetchArtists(ctx context.Context) ([]ArtistWithCustomImage, error) {
+	dataset, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return dataset.Artists, nil
+}
+
+// FetchArtistConcerts retourne les concerts d'un artiste à partir des dates et lieux en mémoire
+func (s *Store) FetchArtistConcerts(ctx context.Context, artistID int) (*ArtistConcerts, error) {
+	dataset, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	datesIndex, foundDates := dataset.datesByID[artistID]
+	locationsIndex, foundLocations := dataset.locationsByID[artistID]
+	if !foundDates || !foundLocations {
+		return nil, fmt.Errorf("%w: %d", ErrArtistNotFound, artistID)
+	}
+
+	return combineConcerts(artistID, dataset.Dates[datesIndex].Dates, dataset.Locations[locationsIndex].Locations), nil
+}
+
+// FetchLocations retourne la relation dates/lieux d'un artiste
+func (s *Store) FetchLocations(ctx context.Context, artistID int) (Relation, error) {
+	dataset, err := s.Snapshot()
+	if err != nil {
+		return Relation{}, err
+	}
+
+	index, found := dataset.relationsByID[artistID]
+	if !found {
+		return Relation{}, fmt.Errorf("%w: %d", ErrArtistNotFound, artistID)
+	}
+	return dataset.Relations[index], nil
+}
+
+// FetchAllLocations retourne les lieux de concerts de tous les artistes
+func (s *Store) FetchAllLocations(ctx context.Context) ([]Location, error) {
+	dataset, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return dataset.Locations, nil
+}
+
+// FetchAllRelations retourne les relations dates/lieux de tous les artistes
+func (s *Store) FetchAllRelations(ctx context.Context) ([]Relation, error) {
+	dataset, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return dataset.Relations, nil
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// GazetteerEntry est un lieu connu du gazetteer local
+type GazetteerEntry struct {
+	Slug        string  `json:"slug"` // slug "ville-pays" du jeu de données
+	Name        string  `json:"name"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	CountryCode string  `json:"countryCode,omitempty"`
+	Population  int     `json:"population,omitempty"`
+}
+
+// Gazetteer résout les lieux à partir d'un fichier local, sans accès réseau
+type Gazetteer struct {
+	entries map[string]GazetteerEntry // slug -> lieu
+}
+
+// LoadGazetteer charge un gazetteer depuis un fichier, selon son extension :
+//   - .json : liste de GazetteerEntry
+//   - .csv  : en-tête avec les colonnes slug,name,lat,lon et country (code ISO ou pays du jeu de données)
+//   - .txt ou .tsv : export GeoNames (ex: cities15000.txt), indexé par nom de ville et code pays
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []GazetteerEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(file).Decode(&entries)
+	case ".csv":
+		entries, err = readGazetteerCSV(file)
+	case ".txt", ".tsv":
+		entries, err = readGeoNames(file)
+	default:
+		err = fmt.Errorf("format de gazetteer non supporté: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gazetteer %s: %w", path, err)
+	}
+
+	gazetteer := &Gazetteer{entries: make(map[string]GazetteerEntry, len(entries))}
+	for _, entry := range entries {
+		if !validLatitude(entry.Lat) || !validLongitude(entry.Lon) {
+			// Coordonnées hors limites : le lieu sera géocodé par le géocodeur suivant
+			continue
+		}
+		// En cas de doublon (homonymes GeoNames), on garde la ville la plus peuplée
+		if existing, found := gazetteer.entries[entry.Slug]; found && existing.Population >= entry.Population {
+			continue
+		}
+		gazetteer.entries[entry.Slug] = entry
+	}
+	return gazetteer, nil
+}
+
+// Len retourne le nombre de lieux connus
+func (g *Gazetteer) Len() int {
+	return len(g.entries)
+}
+
+// Geocode retourne les coordonnées d'un lieu connu, ou ErrNoResult
+func (g *Gazetteer) Geocode(ctx context.Context, location string) (GeocodeResponse, error) {
+	entry, found := g.entries[location]
+	if !found {
+		return GeocodeResponse{}, fmt.Errorf("%w: %s absent du gazetteer", ErrNoResult, location)
+	}
+
+	response := GeocodeResponse{
+		Name:        entry.Name,
+		DisplayName: entry.Name,
+		Lat:         entry.Lat,
+		Lon:         entry.Lon,
+		// Le gazetteer ne connaît qu'un point : la bounding box est réduite à ce point
+		BoundingBox: PointBox(entry.Lat, entry.Lon),
+		Category:    "place",
+		Type:        "city",
+		CountryCode: strings.ToUpper(entry.CountryCode),
+	}
+	return response, nil
+}
+
+// readGazetteerCSV lit un CSV avec en-tête contenant au moins les colonnes slug, lat et lon
+// Les colonnes name et country (ou country_code) sont facultatives
+func readGazetteerCSV(r io.Reader) ([]GazetteerEntry, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"slug", "lat", "lon"} {
+		if _, found := columns[required]; !found {
+			return nil, fmt.Errorf("colonne %q manquante", required)
+		}
+	}
+
+	var entries []GazetteerEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		lat, errLat := strconv.ParseFloat(record[columns["lat"]], 64)
+		lon, errLon := strconv.ParseFloat(record[columns["lon"]], 64)
+		if errLat != nil || errLon != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("ligne %d: coordonnées invalides", line)
+		}
+
+		entry := GazetteerEntry{
+			Slug: record[columns["slug"]],
+			Lat:  lat,
+			Lon:  lon,
+		}
+		if index, found := columns["name"]; found {
+			entry.Name = record[index]
+		}
+		if entry.Name == "" {
+			entry.Name = formatLocation(entry.Slug)
+		}
+		for _, column := range []string{"country_code", "country"} {
+			if index, found := columns[column]; found && entry.CountryCode == "" {
+				entry.CountryCode = csvCountryCode(record[index])
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// csvCountryCode lit le pays d'une ligne de gazetteer CSV : un code ISO ("FR")
+// ou un pays écrit comme dans le jeu de données ("france", "new_zealand")
+// Retourne une chaîne vide pour un pays inconnu
+func csvCountryCode(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) == 2 {
+		return strings.ToUpper(value)
+	}
+	code, _ := CountryCode(Slugify(value))
+	return code
+}
+
+// Colonnes utiles d'un export GeoNames (fichiers "cities*.txt", séparés par des tabulations)
+// Voir https://download.geonames.org/export/dump/readme.txt
+const (
+	geoNamesName        = 1
+	geoNamesASCIIName   = 2
+	geoNamesLat         = 4
+	geoNamesLon         = 5
+	geoNamesCountryCode = 8
+	geoNamesPopulation  = 14
+	geoNamesColumns     = 19
+)
+
+// readGeoNames lit un export GeoNames et produit un lieu par slug "ville-pays"
+// Les pays du jeu de données étant écrits en toutes lettres, seuls ceux connus de countryCodes sont gardés
+func readGeoNames(r io.Reader) ([]GazetteerEntry, error) {
+	// Plusieurs noms peuvent partager un même code (ex: czechia, czech_republic)
+	countries := make(map[string][]string, len(countryCodes))
+	for country, code := range countryCodes {
+		countries[code] = append(countries[code], country)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	var entries []GazetteerEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < geoNamesColumns {
+			continue
+		}
+
+		lat, errLat := strconv.ParseFloat(record[geoNamesLat], 64)
+		lon, errLon := strconv.ParseFloat(record[geoNamesLon], 64)
+		if errLat != nil || errLon != nil {
+			continue
+		}
+		population, _ := strconv.Atoi(record[geoNamesPopulation])
+		countryCode := record[geoNamesCountryCode]
+		city := Slugify(record[geoNamesASCIIName])
+
+		for _, country := range countries[countryCode] {
+			entries = append(entries, GazetteerEntry{
+				Slug:        city + "-" + country,
+				Name:        record[geoNamesName],
+				Lat:         lat,
+				Lon:         lon,
+				CountryCode: countryCode,
+				Population:  population,
+			})
+		}
+	}
+	return entries, nil
+}
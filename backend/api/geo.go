@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// EarthRadiusKm est le rayon moyen de la Terre, en kilomètres
+const EarthRadiusKm = 6371.0088
+
+// MaxDistanceKm est la plus grande distance possible entre deux points : la moitié d'un grand cercle
+const MaxDistanceKm = math.Pi * EarthRadiusKm
+
+// BoundingBox est un rectangle géographique en degrés décimaux
+type BoundingBox struct {
+	South float64 `json:"south"`
+	North float64 `json:"north"`
+	West  float64 `json:"west"`
+	East  float64 `json:"east"`
+}
+
+// PointBox retourne la bounding box réduite au point (lat, lon)
+func PointBox(lat, lon float64) BoundingBox {
+	return BoundingBox{South: lat, North: lat, West: lon, East: lon}
+}
+
+// Validate vérifie que la bounding box est dans les bornes et que sud <= nord
+// Ouest peut être supérieur à est quand la bounding box traverse l'antiméridien
+func (b BoundingBox) Validate() error {
+	if !validLatitude(b.South) || !validLatitude(b.North) || b.South > b.North {
+		return fmt.Errorf("bounding box invalide: sud %v, nord %v", b.South, b.North)
+	}
+	if !validLongitude(b.West) || !validLongitude(b.East) {
+		return fmt.Errorf("bounding box invalide: ouest %v, est %v", b.West, b.East)
+	}
+	return nil
+}
+
+// Contains indique si le point (lat, lon) est dans la bounding box
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return lon >= b.West && lon <= b.East
+	}
+	// La bounding box traverse l'antiméridien
+	return lon >= b.West || lon <= b.East
+}
+
+// validateCoordinates vérifie un point et sa bounding box
+func validateCoordinates(lat, lon float64, box BoundingBox) error {
+	if !validLatitude(lat) {
+		return fmt.Errorf("latitude %v hors de [-90, 90]", lat)
+	}
+	if !validLongitude(lon) {
+		return fmt.Errorf("longitude %v hors de [-180, 180]", lon)
+	}
+	if err := box.Validate(); err != nil {
+		return err
+	}
+	if !box.Contains(lat, lon) {
+		return errors.New("le point n'est pas dans la bounding box")
+	}
+	return nil
+}
+
+// validLatitude indique si lat est une latitude valide
+func validLatitude(lat float64) bool {
+	return !math.IsNaN(lat) && lat >= -90 && lat <= 90
+}
+
+// validLongitude indique si lon est une longitude valide
+func validLongitude(lon float64) bool {
+	return !math.IsNaN(lon) && lon >= -180 && lon <= 180
+}
+
+// FormatCoordinate écrit une coordonnée en texte, sans perte de précision
+// Utilisé par l'API v1, qui renvoie les coordonnées en texte comme Nominatim
+func FormatCoordinate(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// Distance retourne la distance orthodromique (par le grand cercle) entre a et b, en kilomètres
+// Formule de haversine, précise à quelques mètres près pour toutes les distances
+func Distance(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// RoundKm arrondit une distance en kilomètres à 100 mètres près
+func RoundKm(distance float64) float64 {
+	return math.Round(distance*10) / 10
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrGeocoderUnavailable est renvoyée quand aucun géocodeur ne peut chercher le lieu, par exemple
+// en mode hors-ligne : contrairement à ErrNoResult, le lieu existe peut-être et ne doit pas
+// être mémorisé comme introuvable
+var ErrGeocoderUnavailable = errors.New("aucun géocodeur disponible")
+
+// Geocoder convertit un lieu du jeu de données ("ville-pays") en coordonnées géographiques
+// Doit retourner ErrNoResult (éventuellement enveloppée) quand le lieu est inconnu
+// Les appels réseau sont abandonnés quand ctx est annulé
+type Geocoder interface {
+	Geocode(ctx context.Context, location string) (GeocodeResponse, error)
+}
+
+// SplitLocation sépare un slug "ville-pays" en ville et pays, ex: "sao_paulo-brazil" -> "sao_paulo", "brazil"
+func SplitLocation(location string) (city string, country string, err error) {
+	index := strings.LastIndex(location, "-")
+	if index <= 0 || index == len(location)-1 {
+		return "", "", fmt.Errorf("format de location invalide: %s", location)
+	}
+	return location[:index], location[index+1:], nil
+}
+
+// Geocode géocode un slug "ville-pays" via Nominatim
+func (n *NominatimClient) Geocode(ctx context.Context, location string) (GeocodeResponse, error) {
+	city, country, err := SplitLocation(location)
+	if err != nil {
+		return GeocodeResponse{}, err
+	}
+	return n.Search(ctx, city, country)
+}
+
+// Chain essaie plusieurs géocodeurs dans l'ordre et retourne le premier résultat trouvé
+// Typiquement un gazetteer local d'abord, puis Nominatim pour les lieux inconnus
+type Chain []Geocoder
+
+// Geocode interroge chaque géocodeur jusqu'à obtenir un résultat
+// Si tous échouent, retourne la dernière erreur autre que ErrNoResult, sinon ErrNoResult
+// Une chaîne vide retourne ErrGeocoderUnavailable
+func (c Chain) Geocode(ctx context.Context, location string) (GeocodeResponse, error) {
+	if len(c) == 0 {
+		return GeocodeResponse{}, fmt.Errorf("%w: %s", ErrGeocoderUnavailable, location)
+	}
+
+	var lastErr error
+	for _, geocoder := range c {
+		response, err := geocoder.Geocode(ctx, location)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return GeocodeResponse{}, ctx.Err()
+		}
+		if !errors.Is(err, ErrNoResult) {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return GeocodeResponse{}, lastErr
+	}
+	return GeocodeResponse{}, fmt.Errorf("%w: %s", ErrNoResult, location)
+}
+
+// Offline remplace Nominatim en mode hors-ligne, en fin de Chain : les lieux que les géocodeurs
+// locaux ne connaissent pas sont signalés comme non géocodables, pas comme introuvables
+type Offline struct{}
+
+// Geocode retourne toujours ErrGeocoderUnavailable
+func (Offline) Geocode(ctx context.Context, location string) (GeocodeResponse, error) {
+	return GeocodeResponse{}, fmt.Errorf("%w (mode hors-ligne): %s", ErrGeocoderUnavailable, location)
+}
@@ -0,0 +1,103 @@
+package api
+
+import "sort"
+
+// FeatureCollection est une collection GeoJSON (RFC 7946), lisible par Leaflet, QGIS, etc.
+type FeatureCollection struct {
+	Type     string    `json:"type"` // toujours "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature est un objet GeoJSON : une géométrie et ses propriétés
+type Feature struct {
+	Type       string             `json:"type"` // toujours "Feature"
+	ID         string             `json:"id"`   // slug du lieu
+	BBox       []float64          `json:"bbox,omitempty"`
+	Geometry   Geometry           `json:"geometry"`
+	Properties LocationProperties `json:"properties"`
+}
+
+// Geometry est une géométrie GeoJSON ; seuls les points sont utilisés
+type Geometry struct {
+	Type        string     `json:"type"`        // toujours "Point"
+	Coordinates [2]float64 `json:"coordinates"` // [longitude, latitude], dans cet ordre en GeoJSON
+}
+
+// LocationProperties sont les propriétés d'un lieu de concerts
+type LocationProperties struct {
+	Name        string   `json:"name"`
+	Slug        string   `json:"slug"`
+	Country     string   `json:"country"`
+	CountryCode string   `json:"countryCode,omitempty"`
+	Type        string   `json:"type,omitempty"` // type de lieu Nominatim, ex: "city", "state"
+	Dates       []string `json:"dates"`          // dates des concerts, au format ISO 8601 (AAAA-MM-JJ)
+	Artists     []string `json:"artists"`        // noms des artistes ayant joué à cet endroit
+}
+
+// NewFeatureCollection crée une collection à partir de features, triées par slug
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].ID < features[j].ID
+	})
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// NewLocationFeature crée le point GeoJSON d'un lieu de concerts à partir de ses coordonnées,
+// des dates brutes de l'API ("23-08-2019") et des artistes qui y ont joué
+func NewLocationFeature(slug string, coordinates GeocodeResponse, rawDates []string, artists []string) Feature {
+	_, country, _ := SplitLocation(slug)
+	countryCode, _ := CountryCode(country)
+	box := coordinates.BoundingBox
+
+	return Feature{
+		Type: "Feature",
+		ID:   slug,
+		// Ordre GeoJSON : [ouest, sud, est, nord]
+		BBox: []float64{box.West, box.South, box.East, box.North},
+		Geometry: Geometry{
+			Type:        "Point",
+			Coordinates: [2]float64{coordinates.Lon, coordinates.Lat},
+		},
+		Properties: LocationProperties{
+			Name:        coordinates.Name,
+			Slug:        slug,
+			Country:     formatCountry(country),
+			CountryCode: countryCode,
+			Type:        coordinates.Type,
+			Dates:       isoDates(rawDates),
+			Artists:     sortedUnique(artists),
+		},
+	}
+}
+
+// isoDates convertit des dates de l'API au format ISO 8601, triées et sans doublons
+// Les dates illisibles sont ignorées
+func isoDates(rawDates []string) []string {
+	dates := make([]string, 0, len(rawDates))
+	for _, raw := range rawDates {
+		date, err := ParseConcertDate(raw)
+		if err != nil {
+			continue
+		}
+		dates = append(dates, date.Format("2006-01-02"))
+	}
+	// Le format ISO se trie dans l'ordre chronologique
+	return sortedUnique(dates)
+}
+
+// sortedUnique retourne les valeurs triées, sans doublons
+func sortedUnique(values []string) []string {
+	unique := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// icalDateLayout est le format des dates sans heure en iCalendar, ex: "20190823"
+	icalDateLayout = "20060102"
+	// icalUIDDomain termine les UID des événements : il ne doit jamais changer, sinon les
+	// applications abonnées au calendrier dupliqueraient tous les concerts
+	icalUIDDomain = "groupie-tracker"
+	// icalLineOctets est la longueur maximum d'une ligne iCalendar, sans le CRLF
+	icalLineOctets = 75
+)
+
+// WriteCalendar écrit les concerts d'un artiste au format iCalendar (RFC 5545), un VEVENT par concert
+// Les concerts durent toute la journée : l'heure n'est pas connue
+// Le lieu est le nom complet du lieu géocodé quand il est en cache, avec ses coordonnées dans GEO
+func WriteCalendar(w io.Writer, artist Artist, concerts []Concert, now time.Time) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeICalLine(out, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Groupie Tracker//Concerts//FR")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeICalText("Concerts de "+artist.Name))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, concert := range concerts {
+		place := concertPlace(concert)
+
+		line("BEGIN", "VEVENT")
+		line("UID", concertUID(concert))
+		line("DTSTAMP", stamp)
+		line("DTSTART;VALUE=DATE", concert.Date.Format(icalDateLayout))
+		line("DTEND;VALUE=DATE", concert.Date.AddDate(0, 0, 1).Format(icalDateLayout))
+		line("SUMMARY", escapeICalText(artist.Name+" - "+place))
+		line("LOCATION", escapeICalText(concertLocation(concert, place)))
+		if concert.Point != nil {
+			line("GEO", fmt.Sprintf("%s;%s", FormatCoordinate(concert.Point.Lat), FormatCoordinate(concert.Point.Lon)))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+// concertUID retourne l'identifiant stable d'un concert : le même concert garde le même UID
+// d'un téléchargement à l'autre, pour être mis à jour et non dupliqué
+func concertUID(concert Concert) string {
+	return fmt.Sprintf("%d-%s-%s@%s", concert.ArtistID, concert.Date.Format(icalDateLayout), concert.Slug, icalUIDDomain)
+}
+
+// concertPlace retourne le nom court du lieu d'un concert, ex: "Belo Horizonte, Brazil"
+func concertPlace(concert Concert) string {
+	place := concert.City
+	if place == "" {
+		place = concert.Region
+	}
+	if place == "" {
+		return concert.Country
+	}
+	return place + ", " + concert.Country
+}
+
+// concertLocation retourne le nom complet du lieu géocodé s'il est en cache, sinon place
+func concertLocation(concert Concert, place string) string {
+	if coordinates, found := GetFromCache(concert.Slug); found && coordinates.DisplayName != "" {
+		return coordinates.DisplayName
+	}
+	return place
+}
+
+// escapeICalText échappe une valeur de type TEXT (RFC 5545, section 3.3.11)
+func escapeICalText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// writeICalLine écrit une ligne terminée par CRLF, repliée tous les 75 octets
+// (RFC 5545, section 3.1) sans couper de caractère UTF-8
+func writeICalLine(out *bufio.Writer, content string) {
+	limit := icalLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		out.WriteString(content[:cut])
+		out.WriteString("\r\n ")
+		content = content[cut:]
+		// L'espace de début de ligne compte dans la longueur
+		limit = icalLineOctets - 1
+	}
+	out.WriteString(content)
+	out.WriteString("\r\n")
+}
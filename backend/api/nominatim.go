@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"groupie-tracker/config"
+)
+
+// ErrNoResult est renvoyée quand Nominatim ne trouve aucun lieu correspondant
+var ErrNoResult = errors.New("aucun résultat de géocodage")
+
+// maxRetryWait plafonne l'attente entre deux tentatives, backoff comme Retry-After :
+// un en-tête aberrant ne doit pas bloquer la requête et toute la file d'attente derrière elle
+const maxRetryWait = 30 * time.Second
+
+// NominatimClient interroge l'API Nominatim d'OpenStreetMap en respectant sa politique d'usage :
+// une requête par seconde au maximum pour tout le processus, un User-Agent identifiable,
+// et des nouvelles tentatives espacées quand le serveur est saturé (429/5xx)
+// Voir https://operations.osmfoundation.org/policies/nominatim/
+type NominatimClient struct {
+	BaseURL     string        // adresse de l'API Nominatim (sans "/" final)
+	HTTPClient  *http.Client  // client HTTP utilisé pour les requêtes
+	UserAgent   string        // User-Agent identifiant l'application
+	Email       string        // email de contact transmis à Nominatim (optionnel)
+	MinInterval time.Duration // délai minimum entre deux requêtes
+	MaxRetries  int           // nombre de nouvelles tentatives sur 429/5xx ou erreur réseau
+
+	// turn sérialise les appels : les appelants concurrents attendent leur tour
+	// (un canal plutôt qu'un mutex, pour pouvoir abandonner l'attente si le contexte est annulé)
+	// Il est créé au premier appel, un NominatimClient{} construit à la main fonctionne donc aussi
+	turn        chan struct{}
+	turnOnce    sync.Once
+	lastRequest time.Time
+}
+
+// NewNominatimClient crée un client Nominatim limité à une requête par seconde
+func NewNominatimClient(baseURL string, httpClient *http.Client, userAgent, email string) *NominatimClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &NominatimClient{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		HTTPClient:  httpClient,
+		UserAgent:   userAgent,
+		Email:       email,
+		MinInterval: time.Second,
+		MaxRetries:  3,
+	}
+}
+
+// NewNominatimClientFromConfig crée un client Nominatim à partir des variables d'environnement
+func NewNominatimClientFromConfig() *NominatimClient {
+	return NewNominatimClient(
+		config.GetNominatimURL(),
+		&http.Client{Timeout: config.GetUpstreamTimeout()},
+		config.GetUserAgent(),
+		config.GetNominatimEmail(),
+	)
+}
+
+// Search géocode une ville dans un pays, ex: Search("sao_paulo", "brazil")
+// L'attente de son tour, les pauses entre tentatives et la requête s'arrêtent si ctx est annulé
+func (n *NominatimClient) Search(ctx context.Context, city, country string) (GeocodeResponse, error) {
+	query := url.Values{}
+	query.Set("q", strings.ReplaceAll(city, "_", " ")+", "+strings.ReplaceAll(country, "_", " "))
+	query.Set("format", "jsonv2")
+	query.Set("accept-language", "fr")
+	query.Set("limit", "1")
+	// addressdetails donne le pays trouvé, pour vérifier qu'il correspond à celui du slug
+	query.Set("addressdetails", "1")
+	if n.Email != "" {
+		query.Set("email", n.Email)
+	}
+	searchURL := n.BaseURL + "/search?" + query.Encode()
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := n.do(ctx, searchURL)
+		if err == nil {
+			return parseGeocodeResponse(body)
+		}
+		if retryAfter < 0 || attempt >= n.MaxRetries || ctx.Err() != nil {
+			return GeocodeResponse{}, err
+		}
+
+		// Attendre avant de réessayer : Retry-After si le serveur l'indique, sinon backoff exponentiel
+		wait := min(backoff, maxRetryWait)
+		if retryAfter > 0 {
+			wait = min(retryAfter, maxRetryWait)
+		}
+		log.Printf("Nominatim indisponible (%v), nouvel essai dans %s", err, wait)
+		if err := sleep(ctx, wait); err != nil {
+			return GeocodeResponse{}, err
+		}
+		backoff *= 2
+	}
+}
+
+// do envoie une requête en respectant l'intervalle minimum entre deux appels
+// retryAfter vaut -1 si l'erreur est définitive, 0 ou le délai demandé par le serveur sinon
+func (n *NominatimClient) do(ctx context.Context, searchURL string) (body []byte, retryAfter time.Duration, err error) {
+	n.turnOnce.Do(func() { n.turn = make(chan struct{}, 1) })
+	select {
+	case n.turn <- struct{}{}:
+	case <-ctx.Done():
+		return nil, -1, ctx.Err()
+	}
+	defer func() { <-n.turn }()
+
+	if wait := n.MinInterval - time.Since(n.lastRequest); wait > 0 {
+		if err := sleep(ctx, wait); err != nil {
+			return nil, -1, err
+		}
+	}
+	defer func() { n.lastRequest = time.Now() }()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	request.Header.Set("User-Agent", n.UserAgent)
+
+	response, err := n.HTTPClient.Do(request)
+	if err != nil {
+		// Erreur réseau : on peut réessayer
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusOK:
+		body, err = io.ReadAll(response.Body)
+		if err != nil {
+			return nil, 0, err
+		}
+		return body, 0, nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return nil, parseRetryAfter(response.Header.Get("Retry-After")), fmt.Errorf("nominatim: statut %s", response.Status)
+	default:
+		return nil, -1, fmt.Errorf("nominatim: statut %s", response.Status)
+	}
+}
+
+// sleep attend pendant d, ou retourne l'erreur de ctx s'il est annulé avant
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter lit l'en-tête Retry-After exprimé en secondes (0 si absent ou invalide)
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// nominatimPlace est un résultat de recherche de l'API Nominatim, coordonnées en texte
+type nominatimPlace struct {
+	Licence     string    `json:"licence"`
+	OsmType     string    `json:"osm_type"`
+	OsmID       int64     `json:"osm_id"`
+	BoundingBox [4]string `json:"boundingbox"` // [sud, nord, ouest, est]
+	Lat         string    `json:"lat"`
+	Lon         string    `json:"lon"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	Category    string    `json:"category"`
+	Type        string    `json:"addresstype"`
+	Importance  float64   `json:"importance"`
+	Address     struct {
+		CountryCode string `json:"country_code"` // en minuscules
+	} `json:"address"`
+}
+
+// response convertit les coordonnées textuelles en nombres et les vérifie
+func (p nominatimPlace) response() (GeocodeResponse, error) {
+	lat, err := strconv.ParseFloat(p.Lat, 64)
+	if err != nil {
+		return GeocodeResponse{}, fmt.Errorf("latitude invalide %q", p.Lat)
+	}
+	lon, err := strconv.ParseFloat(p.Lon, 64)
+	if err != nil {
+		return GeocodeResponse{}, fmt.Errorf("longitude invalide %q", p.Lon)
+	}
+
+	var box [4]float64
+	for i, raw := range p.BoundingBox {
+		if box[i], err = strconv.ParseFloat(raw, 64); err != nil {
+			return GeocodeResponse{}, fmt.Errorf("bounding box invalide %q", p.BoundingBox)
+		}
+	}
+
+	response := GeocodeResponse{
+		Name:        p.Name,
+		DisplayName: p.DisplayName,
+		Lat:         lat,
+		Lon:         lon,
+		BoundingBox: BoundingBox{South: box[0], North: box[1], West: box[2], East: box[3]},
+		Category:    p.Category,
+		Type:        p.Type,
+		CountryCode: strings.ToUpper(p.Address.CountryCode),
+		Importance:  p.Importance,
+		OsmType:     p.OsmType,
+		OsmID:       p.OsmID,
+		Licence:     p.Licence,
+	}
+	if response.CountryCode == "" {
+		// Lieux géocodés sans addressdetails, comme ceux des anciens fichiers de cache
+		response.CountryCode, _ = CountryCodeFromDisplayName(p.DisplayName)
+	}
+	if err := response.Validate(); err != nil {
+		return GeocodeResponse{}, err
+	}
+	return response, nil
+}
+
+// parseGeocodeResponse décode la réponse de Nominatim et vérifie qu'elle contient un lieu valide
+func parseGeocodeResponse(body []byte) (GeocodeResponse, error) {
+	var results []nominatimPlace
+	if err := json.Unmarshal(body, &results); err != nil {
+		return GeocodeResponse{}, err
+	}
+	if len(results) == 0 {
+		return GeocodeResponse{}, ErrNoResult
+	}
+
+	response, err := results[0].response()
+	if err != nil {
+		return GeocodeResponse{}, fmt.Errorf("réponse de Nominatim invalide: %w", err)
+	}
+	return response, nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"strings"
+	"unicode"
+)
+
+// accents associe les lettres accentuées courantes à leur équivalent sans accent
+var accents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "c",
+	'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r",
+	'ś': "s", 'š': "s", 'ş': "s", 'ș': "s",
+	'ť': "t", 'ţ': "t", 'ț': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'æ': "ae", 'œ': "oe", 'ß': "ss", 'þ': "th", 'ð': "d",
+}
+
+// Fold met un texte en minuscules et enlève les accents, ex: "Beyoncé" -> "beyonce"
+func Fold(s string) string {
+	var builder strings.Builder
+	builder.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		if replacement, found := accents[r]; found {
+			builder.WriteString(replacement)
+			continue
+		}
+		if unicode.Is(unicode.Mn, r) {
+			// Accent combinant isolé (forme décomposée) : on l'ignore
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// Slugify convertit un nom de lieu au format des slugs du jeu de données
+// ex: "São Paulo" -> "sao_paulo"
+func Slugify(s string) string {
+	fields := strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, "_")
+}
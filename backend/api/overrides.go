@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"groupie-tracker/config"
+)
+
+// Override fixe à la main les coordonnées d'un lieu mal géocodé
+// Une correction est prioritaire sur le cache et sur le géocodeur
+type Override struct {
+	Name        string       `json:"name,omitempty"` // nom affiché (défaut: la ville du slug)
+	Lat         float64      `json:"lat"`
+	Lon         float64      `json:"lon"`
+	BoundingBox *BoundingBox `json:"boundingBox,omitempty"` // défaut: réduite au point
+	Type        string       `json:"type,omitempty"`        // type de lieu, ex: "city", "state" (défaut: "city")
+	Note        string       `json:"note,omitempty"`        // raison de la correction, pour les opérateurs
+}
+
+// Validate vérifie que les coordonnées de la correction sont valides
+func (o Override) Validate() error {
+	return validateCoordinates(o.Lat, o.Lon, o.box())
+}
+
+// box retourne la bounding box de la correction, réduite au point si elle n'est pas précisée
+func (o Override) box() BoundingBox {
+	if o.BoundingBox != nil {
+		return *o.BoundingBox
+	}
+	return PointBox(o.Lat, o.Lon)
+}
+
+// response convertit la correction au format renvoyé par le géocodeur
+func (o Override) response(location string) GeocodeResponse {
+	city, country, _ := SplitLocation(location)
+	countryCode, _ := CountryCode(country)
+
+	response := GeocodeResponse{
+		Name:        o.Name,
+		Lat:         o.Lat,
+		Lon:         o.Lon,
+		BoundingBox: o.box(),
+		Category:    "place",
+		Type:        o.Type,
+		CountryCode: countryCode,
+	}
+	if response.Name == "" {
+		response.Name = formatPlace(city)
+	}
+	response.DisplayName = response.Name
+	if response.Type == "" {
+		response.Type = "city"
+	}
+	return response
+}
+
+// overrides contient les corrections manuelles, relues depuis le fichier OVERRIDES_FILE au démarrage
+var overrides = struct {
+	mu         sync.RWMutex
+	path       string
+	entries    map[string]Override
+	generation atomic.Uint64 // incrémenté à chaque modification
+}{
+	path:    config.GetOverridesFile(),
+	entries: make(map[string]Override),
+}
+
+// init charge les corrections manuelles au démarrage du programme
+func init() {
+	content, err := os.ReadFile(overrides.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		log.Printf("Erreur lors du chargement des corrections: %v", err)
+		return
+	}
+
+	var entries map[string]Override
+	if err := json.Unmarshal(content, &entries); err != nil {
+		log.Printf("Fichier de corrections %s illisible: %v", overrides.path, err)
+		return
+	}
+	for location, override := range entries {
+		if err := override.Validate(); err != nil {
+			log.Printf("Correction ignorée pour %s: %v", location, err)
+			continue
+		}
+		overrides.entries[location] = override
+	}
+	log.Printf("%d corrections de coordonnées chargées", len(overrides.entries))
+}
+
+// overrideResponse retourne les coordonnées corrigées à la main d'un lieu, s'il y en a
+func overrideResponse(location string) (GeocodeResponse, bool) {
+	overrides.mu.RLock()
+	override, found := overrides.entries[location]
+	overrides.mu.RUnlock()
+
+	if !found {
+		return GeocodeResponse{}, false
+	}
+	return override.response(location), true
+}
+
+// Overrides retourne une copie de toutes les corrections manuelles
+func Overrides() map[string]Override {
+	overrides.mu.RLock()
+	defer overrides.mu.RUnlock()
+
+	entries := make(map[string]Override, len(overrides.entries))
+	for location, override := range overrides.entries {
+		entries[location] = override
+	}
+	return entries
+}
+
+// GetOverride retourne la correction manuelle d'un lieu, telle qu'enregistrée
+func GetOverride(location string) (Override, bool) {
+	overrides.mu.RLock()
+	defer overrides.mu.RUnlock()
+
+	override, found := overrides.entries[location]
+	return override, found
+}
+
+// SetOverride ajoute ou remplace la correction d'un lieu et l'enregistre dans le fichier
+func SetOverride(location string, override Override) error {
+	if _, _, err := SplitLocation(location); err != nil {
+		return err
+	}
+	if err := override.Validate(); err != nil {
+		return err
+	}
+
+	overrides.mu.Lock()
+	defer overrides.mu.Unlock()
+
+	previous, existed := overrides.entries[location]
+	overrides.entries[location] = override
+	if err := saveOverrides(); err != nil {
+		// Ne pas garder en mémoire une correction qui serait perdue au redémarrage
+		if existed {
+			overrides.entries[location] = previous
+		} else {
+			delete(overrides.entries, location)
+		}
+		return err
+	}
+	overrides.generation.Add(1)
+	return nil
+}
+
+// DeleteOverride supprime la correction d'un lieu et indique si elle existait
+func DeleteOverride(location string) (bool, error) {
+	overrides.mu.Lock()
+	defer overrides.mu.Unlock()
+
+	previous, found := overrides.entries[location]
+	if !found {
+		return false, nil
+	}
+	delete(overrides.entries, location)
+	if err := saveOverrides(); err != nil {
+		overrides.entries[location] = previous
+		return true, err
+	}
+	overrides.generation.Add(1)
+	return true, nil
+}
+
+// saveOverrides écrit les corrections dans le fichier, de manière atomique
+// Le fichier reste du JSON simple, sans en-tête, pour pouvoir être modifié à la main
+// Doit être appelée avec overrides.mu verrouillé
+func saveOverrides() error {
+	content, err := json.MarshalIndent(overrides.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(overrides.path, append(content, '\n'))
+}
+
+// CountryMismatch est un lieu dont le pays trouvé par le géocodeur n'est pas celui de son slug
+type CountryMismatch struct {
+	Location        string `json:"location"`
+	ExpectedCountry string `json:"expectedCountry"` // code ISO du pays du slug
+	GeocodedCountry string `json:"geocodedCountry"` // code ISO du pays trouvé par le géocodeur
+	DisplayName     string `json:"displayName"`
+}
+
+// CountryReport liste les lieux du cache probablement mal géocodés
+type CountryReport struct {
+	Mismatches []CountryMismatch `json:"mismatches"`
+	// Unverified sont les lieux dont le pays n'a pas pu être comparé : pays du slug inconnu,
+	// ou coordonnées en cache sans pays (géocodées avant l'ajout de cette information)
+	Unverified []string `json:"unverified"`
+}
+
+// CheckCountries compare, pour chaque lieu du cache de coordonnées, le pays trouvé par le géocodeur
+// à celui du slug. Les lieux corrigés à la main et les lieux sans résultat sont ignorés
+func CheckCountries() CountryReport {
+	report := CountryReport{Mismatches: []CountryMismatch{}, Unverified: []string{}}
+
+	for _, entry := range coordinatesCache.Entries() {
+		if entry.Negative {
+			continue
+		}
+		if _, overridden := GetOverride(entry.Key); overridden {
+			continue
+		}
+
+		geocoded := entry.Value.CountryCode
+		expected := ""
+		if _, country, err := SplitLocation(entry.Key); err == nil {
+			expected, _ = CountryCode(country)
+		}
+		if expected == "" || geocoded == "" {
+			report.Unverified = append(report.Unverified, entry.Key)
+			continue
+		}
+		if !SameCountry(expected, geocoded) {
+			report.Mismatches = append(report.Mismatches, CountryMismatch{
+				Location:        entry.Key,
+				ExpectedCountry: expected,
+				GeocodedCountry: geocoded,
+				DisplayName:     entry.Value.DisplayName,
+			})
+		}
+	}
+
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Location < report.Mismatches[j].Location
+	})
+	sort.Strings(report.Unverified)
+	return report
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// ForEachLimit appelle fn pour chaque index de 0 à n-1, avec au plus limit appels simultanés
+// Au premier échec, le contexte passé aux autres appels est annulé et plus aucun appel n'est lancé
+// Retourne la première erreur rencontrée, ou l'erreur de ctx s'il est annulé avant la fin
+func ForEachLimit(ctx context.Context, n, limit int, fn func(ctx context.Context, i int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// slots limite le nombre de goroutines actives : chaque appel prend une place et la rend à la fin
+	slots := make(chan struct{}, limit)
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+		case slots <- struct{}{}:
+		}
+		if err := ctx.Err(); err != nil {
+			fail(err)
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(ctx, i); err != nil {
+				fail(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// Parallel exécute des tâches indépendantes en parallèle, avec au plus limit tâches simultanées
+// Même comportement que ForEachLimit en cas d'échec ou d'annulation
+func Parallel(ctx context.Context, limit int, tasks ...func(ctx context.Context) error) error {
+	return ForEachLimit(ctx, len(tasks), limit, func(ctx context.Context, i int) error {
+		return tasks[i](ctx)
+	})
+}
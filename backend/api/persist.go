@@ -0,0 +1,232 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// diskFile enregistre des données JSON sur disque sans risque de corruption :
+//   - écriture dans un fichier temporaire puis renommage (jamais de fichier à moitié écrit)
+//   - en-tête avec version du format et somme de contrôle des données
+//   - copie de la version précédente dans path + ".bak", relue si le fichier principal est illisible ou absent
+type diskFile struct {
+	path    string
+	version int
+}
+
+// diskEnvelope est le contenu d'un fichier écrit par diskFile
+type diskEnvelope struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"` // "sha256:" + hash des données en JSON compact
+	Data     json.RawMessage `json:"data"`
+}
+
+// errLegacyFormat signale un fichier sans en-tête (ancien format : les données brutes)
+var errLegacyFormat = errors.New("fichier sans en-tête de version")
+
+// backupPath retourne le chemin de la copie de secours
+func (f diskFile) backupPath() string {
+	return f.path + ".bak"
+}
+
+// Write enregistre data de manière atomique
+func (f diskFile) Write(data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(raw)
+
+	content, err := json.MarshalIndent(diskEnvelope{
+		Version:  f.version,
+		Checksum: "sha256:" + hex.EncodeToString(sum[:]),
+		Data:     raw,
+	}, "", "  ") // Format JSON lisible
+	if err != nil {
+		return err
+	}
+
+	// Garder la version précédente comme copie de secours, si elle est valide
+	// Copiée et non renommée : le fichier principal existe toujours, même si l'écriture est interrompue
+	if previous, err := os.ReadFile(f.path); err == nil && checkContent(previous) == nil {
+		if err := writeFileAtomic(f.backupPath(), previous); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(f.path, append(content, '\n'))
+}
+
+// Read relit les données dans target, depuis le fichier principal ou à défaut depuis la copie de secours
+// Retourne la version du format lue (0 pour un fichier de l'ancien format sans en-tête),
+// et l'erreur du fichier principal si la copie de secours a dû être utilisée (recovered)
+// Retourne une erreur os.ErrNotExist si ni le fichier ni sa copie de secours n'existent
+func (f diskFile) Read(target any) (version int, recovered error, err error) {
+	version, err = f.read(f.path, target)
+	if err == nil {
+		return version, nil, nil
+	}
+	// Fichier principal corrompu, ou absent après un arrêt entre les deux renommages de Write :
+	// on tente la copie de secours
+	backupVersion, backupErr := f.read(f.backupPath(), target)
+	if backupErr == nil {
+		return backupVersion, fmt.Errorf("%s illisible: %w", f.path, err), nil
+	}
+	return 0, nil, err
+}
+
+// read décode un fichier et vérifie sa somme de contrôle
+func (f diskFile) read(path string, target any) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	envelope, err := decodeEnvelope(content)
+	if errors.Is(err, errLegacyFormat) {
+		// Ancien format : les données sans en-tête, elles seront migrées à la prochaine écriture
+		return 0, json.Unmarshal(content, target)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if envelope.Version > f.version {
+		return 0, fmt.Errorf("version %d non supportée (maximum: %d)", envelope.Version, f.version)
+	}
+	return envelope.Version, json.Unmarshal(envelope.Data, target)
+}
+
+// checkContent vérifie que le contenu d'un fichier est du JSON valide, avec une somme de contrôle correcte
+func checkContent(content []byte) error {
+	_, err := decodeEnvelope(content)
+	if errors.Is(err, errLegacyFormat) {
+		if !json.Valid(content) {
+			return errors.New("JSON invalide")
+		}
+		return nil
+	}
+	return err
+}
+
+// writeFileAtomic remplace le fichier path par content sans jamais laisser de fichier à moitié écrit :
+// écriture dans un fichier temporaire du même dossier, synchronisation sur le disque, puis renommage
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Supprime le fichier temporaire si quelque chose échoue avant le renommage
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	// CreateTemp crée le fichier en 0600 : garder les droits du fichier remplacé, 0644 sinon
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	// S'assurer que les données sont réellement sur le disque avant de remplacer l'ancien fichier
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// decodeEnvelope décode l'en-tête d'un fichier et vérifie la somme de contrôle des données
+func decodeEnvelope(content []byte) (diskEnvelope, error) {
+	var envelope diskEnvelope
+	if err := json.Unmarshal(content, &envelope); err != nil {
+		// Un ancien fichier (map de données) se décode sans erreur ici :
+		// une erreur signifie donc un JSON invalide
+		return diskEnvelope{}, err
+	}
+	if envelope.Version == 0 && envelope.Checksum == "" && envelope.Data == nil {
+		return diskEnvelope{}, errLegacyFormat
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, envelope.Data); err != nil {
+		return diskEnvelope{}, err
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	if envelope.Checksum != "sha256:"+hex.EncodeToString(sum[:]) {
+		return diskEnvelope{}, errors.New("somme de contrôle invalide")
+	}
+	envelope.Data = compact.Bytes()
+	return envelope, nil
+}
+
+// debouncer regroupe des demandes d'écriture rapprochées en une seule, exécutée après un délai
+// Les écritures sont sérialisées : jamais deux exécutions de write en même temps
+type debouncer struct {
+	delay time.Duration
+	write func()
+
+	mu      sync.Mutex    // protège timer et done
+	timer   *time.Timer   // écriture programmée, nil s'il n'y en a pas
+	done    chan struct{} // fermé à la fin de la dernière écriture programmée
+	writing sync.Mutex    // sérialise les appels à write
+}
+
+// Trigger programme une écriture ; les demandes suivantes avant l'échéance sont regroupées
+func (d *debouncer) Trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer == nil {
+		done := make(chan struct{})
+		d.done = done
+		d.timer = time.AfterFunc(d.delay, func() { d.run(done) })
+	}
+}
+
+// Flush exécute immédiatement l'écriture en attente, s'il y en a une, ou attend la fin
+// de celle dont le délai vient d'expirer
+// A appeler avant l'arrêt du programme
+func (d *debouncer) Flush() {
+	d.mu.Lock()
+	done := d.done
+	pending := d.timer != nil && d.timer.Stop()
+	d.mu.Unlock()
+
+	if pending {
+		d.run(done)
+	} else if done != nil {
+		<-done
+	}
+}
+
+// run exécute l'écriture puis ferme done
+func (d *debouncer) run(done chan struct{}) {
+	d.mu.Lock()
+	d.timer = nil
+	d.mu.Unlock()
+
+	d.writing.Lock()
+	d.write()
+	d.writing.Unlock()
+
+	d.mu.Lock()
+	if d.done == done {
+		d.done = nil
+	}
+	d.mu.Unlock()
+	close(done)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup regroupe les appels concurrents identiques : tant qu'un appel pour une clé est en cours,
+// les autres appelants pour la même clé attendent son résultat au lieu de refaire l'appel
+type flightGroup[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[T]
+}
+
+// flightCall est un appel en cours, partagé par un ou plusieurs appelants
+type flightCall[T any] struct {
+	done    chan struct{} // fermé quand l'appel est terminé
+	value   T
+	err     error
+	waiters int                // nombre d'appelants qui attendent encore le résultat
+	cancel  context.CancelFunc // annule l'appel quand plus personne ne l'attend
+}
+
+// Do exécute fn pour key, ou attend le résultat d'un appel déjà en cours pour la même clé
+// L'appel partagé n'est annulé que lorsque tous les appelants ont abandonné (ctx annulé)
+func (g *flightGroup[T]) Do(ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[T])
+	}
+	call, found := g.calls[key]
+	if found {
+		call.waiters++
+	} else {
+		// L'appel ne dépend pas du contexte du premier appelant : s'il abandonne,
+		// les autres doivent quand même obtenir le résultat
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall[T]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = call
+
+		go func() {
+			call.value, call.err = fn(callCtx)
+			cancel()
+
+			g.mu.Lock()
+			g.forget(key, call)
+			g.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// Plus personne n'attend : on annule l'appel et les prochains appelants en relanceront un
+			call.cancel()
+			g.forget(key, call)
+		}
+		g.mu.Unlock()
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// forget retire call des appels en cours, s'il y est encore associé à key
+// Doit être appelée avec g.mu verrouillé
+func (g *flightGroup[T]) forget(key string, call *flightCall[T]) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
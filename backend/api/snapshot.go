@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SnapshotVersion est la version actuelle du format des fichiers de snapshot
+// A incrémenter à chaque changement incompatible de la structure Dataset
+const SnapshotVersion = 1
+
+// Snapshot est le contenu d'un fichier de snapshot : le jeu de données complet et ses métadonnées
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	Source    string    `json:"source"` // URL de l'API d'où viennent les données
+	Dataset   *Dataset  `json:"dataset"`
+}
+
+// WriteSnapshot écrit le jeu de données dans le fichier path, de manière atomique
+func WriteSnapshot(path string, dataset *Dataset, source string) error {
+	snapshot := Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Source:    source,
+		Dataset:   dataset,
+	}
+
+	content, err := json.MarshalIndent(snapshot, "", "  ") // Format JSON lisible
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(content, '\n'))
+}
+
+// ReadSnapshot lit un fichier de snapshot et retourne le jeu de données qu'il contient
+func ReadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(file).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("snapshot %s illisible: %w", path, err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s: version %d non supportée (attendue: %d)", path, snapshot.Version, SnapshotVersion)
+	}
+	if snapshot.Dataset == nil {
+		return nil, fmt.Errorf("snapshot %s: jeu de données manquant", path)
+	}
+
+	// Les URLs d'images dépendent de l'environnement courant, pas de celui du snapshot
+	artists := make([]Artist, len(snapshot.Dataset.Artists))
+	for i, artist := range snapshot.Dataset.Artists {
+		artists[i] = artist.Artist
+	}
+	snapshot.Dataset.Artists = withCustomImages(artists)
+
+	return &snapshot, nil
+}
+
+// FileSource fournit le jeu de données depuis un fichier de snapshot, sans accès réseau
+type FileSource struct {
+	Path string
+}
+
+// FetchDataset relit le fichier de snapshot
+func (f FileSource) FetchDataset(ctx context.Context) (*Dataset, error) {
+	snapshot, err := ReadSnapshot(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Dataset, nil
+}
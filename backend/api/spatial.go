@@ -0,0 +1,120 @@
+package api
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// spatialCellDeg est la taille en degrés des cellules de la grille de SpatialIndex
+// Une cellule de 5° fait environ 550 km de côté à l'équateur : assez fin pour une vue de pays,
+// assez gros pour qu'une vue du monde ne parcoure que quelques milliers de cellules
+const spatialCellDeg = 5
+
+// IndexedLocation est un lieu géocodé de SpatialIndex avec ses concerts, par ordre chronologique
+type IndexedLocation struct {
+	Slug     string
+	Point    GeoPoint
+	Concerts []Concert
+}
+
+// Between retourne les concerts du lieu entre from et to inclus (bornes zéro = pas de limite)
+func (l *IndexedLocation) Between(from, to time.Time) []Concert {
+	start, end := 0, len(l.Concerts)
+	if !from.IsZero() {
+		start = sort.Search(len(l.Concerts), func(i int) bool { return !l.Concerts[i].Date.Before(from) })
+	}
+	if !to.IsZero() {
+		end = sort.Search(len(l.Concerts), func(i int) bool { return l.Concerts[i].Date.After(to) })
+	}
+	if start >= end {
+		return nil
+	}
+	return l.Concerts[start:end]
+}
+
+// spatialCell identifie une cellule de la grille
+type spatialCell struct {
+	lat, lon int
+}
+
+// SpatialIndex range les lieux de concerts géocodés dans une grille régulière,
+// pour retrouver rapidement ceux d'une zone de la carte sans parcourir tous les lieux
+// Un index n'est jamais modifié après sa construction : il peut être lu par plusieurs goroutines
+type SpatialIndex struct {
+	cells     map[spatialCell][]*IndexedLocation
+	locations int
+}
+
+// NewSpatialIndex construit l'index à partir d'une liste de concerts
+// Les concerts sans coordonnées (lieu pas encore géocodé) sont ignorés
+func NewSpatialIndex(concerts []Concert) *SpatialIndex {
+	index := &SpatialIndex{cells: make(map[spatialCell][]*IndexedLocation)}
+
+	bySlug := make(map[string]*IndexedLocation)
+	for _, concert := range concerts {
+		if concert.Point == nil {
+			continue
+		}
+		location, found := bySlug[concert.Slug]
+		if !found {
+			location = &IndexedLocation{Slug: concert.Slug, Point: *concert.Point}
+			bySlug[concert.Slug] = location
+			cell := cellOf(location.Point)
+			index.cells[cell] = append(index.cells[cell], location)
+		}
+		location.Concerts = append(location.Concerts, concert)
+	}
+
+	for _, location := range bySlug {
+		SortConcerts(location.Concerts)
+	}
+	index.locations = len(bySlug)
+	return index
+}
+
+// Len retourne le nombre de lieux de l'index
+func (s *SpatialIndex) Len() int {
+	return s.locations
+}
+
+// Within retourne les lieux situés dans box, triés par slug
+// box peut traverser l'antiméridien (ouest > est)
+func (s *SpatialIndex) Within(box BoundingBox) []*IndexedLocation {
+	var locations []*IndexedLocation
+	for _, lonRange := range lonRanges(box) {
+		minCell := cellOf(GeoPoint{Lat: box.South, Lon: lonRange[0]})
+		maxCell := cellOf(GeoPoint{Lat: box.North, Lon: lonRange[1]})
+		for lat := minCell.lat; lat <= maxCell.lat; lat++ {
+			for lon := minCell.lon; lon <= maxCell.lon; lon++ {
+				for _, location := range s.cells[spatialCell{lat: lat, lon: lon}] {
+					if location.Point.Lat >= box.South && location.Point.Lat <= box.North &&
+						location.Point.Lon >= lonRange[0] && location.Point.Lon <= lonRange[1] {
+						locations = append(locations, location)
+					}
+				}
+			}
+		}
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].Slug < locations[j].Slug
+	})
+	return locations
+}
+
+// lonRanges découpe la bounding box en intervalles de longitude sans traversée de l'antiméridien
+func lonRanges(box BoundingBox) [][2]float64 {
+	if box.West <= box.East {
+		return [][2]float64{{box.West, box.East}}
+	}
+	return [][2]float64{{box.West, 180}, {-180, box.East}}
+}
+
+// cellOf retourne la cellule de la grille contenant point
+func cellOf(point GeoPoint) spatialCell {
+	return spatialCell{
+		lat: int(math.Floor(point.Lat / spatialCellDeg)),
+		lon: int(math.Floor(point.Lon / spatialCellDeg)),
+	}
+}
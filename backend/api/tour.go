@@ -0,0 +1,80 @@
+package api
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultTourGapDays est le nombre de jours sans concert qui sépare deux tournées
+const DefaultTourGapDays = 60
+
+// TourStop est un concert d'une tournée, avec le trajet depuis le concert précédent
+type TourStop struct {
+	Concert
+	LegKm             *float64 `json:"legKm,omitempty"`             // distance depuis le concert précédent, absente si un des lieux n'est pas géocodé
+	DaysSincePrevious *int     `json:"daysSincePrevious,omitempty"` // jours depuis le concert précédent
+}
+
+// Tour est une suite de concerts sans interruption plus longue que l'écart entre tournées
+type Tour struct {
+	Number       int        `json:"number"` // numéro de la tournée, à partir de 1
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	DurationDays int        `json:"durationDays"`
+	Concerts     int        `json:"concerts"`
+	DistanceKm   float64    `json:"distanceKm"`  // somme des trajets connus
+	MissingLegs  int        `json:"missingLegs"` // trajets sans distance, faute de coordonnées
+	Stops        []TourStop `json:"stops"`
+}
+
+// BuildTours découpe des concerts en tournées, séparées par plus de gapDays jours sans concert,
+// et calcule la distance orthodromique de chaque trajet entre deux concerts
+// Les concerts sont triés par ordre chronologique ; les coordonnées sont celles de Concert.Point
+func BuildTours(concerts []Concert, gapDays int) []Tour {
+	sorted := append([]Concert(nil), concerts...)
+	SortConcerts(sorted)
+
+	tours := []Tour{}
+	var current *Tour
+	var previous *Concert
+	for i := range sorted {
+		concert := &sorted[i]
+		stop := TourStop{Concert: *concert}
+
+		if previous != nil {
+			days := daysBetween(previous.Date, concert.Date)
+			if days > gapDays {
+				current = nil
+			} else {
+				stop.DaysSincePrevious = &days
+				if previous.Point != nil && concert.Point != nil {
+					leg := RoundKm(Distance(*previous.Point, *concert.Point))
+					stop.LegKm = &leg
+					current.DistanceKm += leg
+				} else {
+					current.MissingLegs++
+				}
+			}
+		}
+
+		if current == nil {
+			tours = append(tours, Tour{Number: len(tours) + 1, Start: concert.Date})
+			current = &tours[len(tours)-1]
+		}
+		current.Stops = append(current.Stops, stop)
+		current.End = concert.Date
+		current.DurationDays = daysBetween(current.Start, current.End) + 1
+		current.Concerts++
+		previous = concert
+	}
+
+	for i := range tours {
+		tours[i].DistanceKm = RoundKm(tours[i].DistanceKm)
+	}
+	return tours
+}
+
+// daysBetween retourne le nombre de jours calendaires entre deux dates de concert
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
 // GetFrontendURL retourne l'URL du frontend depuis les variables d'environnement
 func GetFrontendURL() string {
@@ -28,3 +33,167 @@ func GetPort() string {
 	}
 	return port
 }
+
+// GetUpstreamURL retourne l'URL de l'API Groupie Tracker interrogée par le backend
+// Permet de pointer vers un miroir, une copie de test ou un faux serveur local
+func GetUpstreamURL() string {
+	upstreamURL := os.Getenv("UPSTREAM_URL")
+	if upstreamURL == "" {
+		upstreamURL = "https://groupietrackers.herokuapp.com/api"
+	}
+	return upstreamURL
+}
+
+// GetUpstreamTimeout retourne le timeout des requêtes vers l'API Groupie Tracker
+// Format accepté par time.ParseDuration (ex: "10s", "500ms")
+func GetUpstreamTimeout() time.Duration {
+	return getDuration("UPSTREAM_TIMEOUT", 10*time.Second)
+}
+
+// GetUserAgent retourne le User-Agent envoyé aux APIs externes
+func GetUserAgent() string {
+	userAgent := os.Getenv("USER_AGENT")
+	if userAgent == "" {
+		userAgent = "groupie-tracker/1.0 (+https://github.com/Konixy/groupie-tracker)"
+	}
+	return userAgent
+}
+
+// getDuration lit une durée depuis une variable d'environnement
+// Retourne la valeur par défaut si la variable est absente ou invalide
+func getDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %s", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// GetDatasetRefreshInterval retourne l'intervalle de rafraîchissement du jeu de données en mémoire
+func GetDatasetRefreshInterval() time.Duration {
+	return getDuration("DATASET_REFRESH_INTERVAL", time.Hour)
+}
+
+// GetSnapshotFile retourne le chemin du snapshot à servir en mode hors-ligne
+// Vide par défaut : les données sont alors récupérées depuis l'API
+func GetSnapshotFile() string {
+	return os.Getenv("SNAPSHOT_FILE")
+}
+
+// GetNominatimURL retourne l'URL de l'API Nominatim utilisée pour le géocodage
+func GetNominatimURL() string {
+	nominatimURL := os.Getenv("NOMINATIM_URL")
+	if nominatimURL == "" {
+		nominatimURL = "https://nominatim.openstreetmap.org"
+	}
+	return nominatimURL
+}
+
+// GetNominatimEmail retourne l'email de contact transmis à Nominatim (recommandé par sa politique d'usage)
+func GetNominatimEmail() string {
+	return os.Getenv("NOMINATIM_EMAIL")
+}
+
+// GetGazetteerFile retourne le chemin d'un gazetteer local (JSON, CSV ou export GeoNames)
+// consulté avant Nominatim. Vide par défaut
+func GetGazetteerFile() string {
+	return os.Getenv("GAZETTEER_FILE")
+}
+
+// getTTL lit une durée de vie de cache depuis une variable d'environnement
+// Contrairement à getDuration, "0" est accepté et signifie "sans expiration" (ou "désactivé")
+func getTTL(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %s", name, value, fallback)
+		return fallback
+	}
+	return duration
+}
+
+// getInt lit un entier supérieur ou égal à min depuis une variable d'environnement
+// Retourne la valeur par défaut si la variable est absente ou invalide
+func getInt(name string, fallback, min int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min {
+		log.Printf("Valeur invalide pour %s (%q), utilisation de %d", name, value, fallback)
+		return fallback
+	}
+	return number
+}
+
+// GetFetchConcurrency retourne le nombre maximum d'appels simultanés vers les APIs externes
+// (récupération des données et géocodage) pour une même opération
+func GetFetchConcurrency() int {
+	return getInt("FETCH_CONCURRENCY", 4, 1)
+}
+
+// GetRequestTimeout retourne la durée maximum de traitement d'une requête entrante
+// Au-delà, les appels en cours vers les APIs externes sont abandonnés
+func GetRequestTimeout() time.Duration {
+	return getDuration("REQUEST_TIMEOUT", 30*time.Second)
+}
+
+// GetUpstreamCacheTTL retourne la durée de conservation des réponses de l'API Groupie Tracker
+// 0 désactive le cache des réponses
+func GetUpstreamCacheTTL() time.Duration {
+	return getTTL("UPSTREAM_CACHE_TTL", 5*time.Minute)
+}
+
+// GetUpstreamNegativeTTL retourne la durée pendant laquelle une ressource introuvable (404)
+// n'est pas redemandée à l'API. 0 désactive le cache négatif
+func GetUpstreamNegativeTTL() time.Duration {
+	return getTTL("UPSTREAM_NEGATIVE_TTL", time.Minute)
+}
+
+// GetUpstreamCacheSize retourne le nombre maximum de réponses de l'API gardées en cache
+// Au-delà, les moins récemment utilisées sont supprimées. 0 = illimité
+func GetUpstreamCacheSize() int {
+	return getInt("UPSTREAM_CACHE_SIZE", 500, 0)
+}
+
+// GetGeocodeCacheTTL retourne la durée de conservation des coordonnées géocodées
+// 0 (défaut) : les coordonnées n'expirent jamais
+func GetGeocodeCacheTTL() time.Duration {
+	return getTTL("GEOCODE_CACHE_TTL", 0)
+}
+
+// GetGeocodeNegativeTTL retourne la durée pendant laquelle un lieu sans résultat n'est pas re-géocodé
+// 0 désactive le cache négatif
+func GetGeocodeNegativeTTL() time.Duration {
+	return getTTL("GEOCODE_NEGATIVE_TTL", 24*time.Hour)
+}
+
+// GetGeocodeCacheSize retourne le nombre maximum de lieux gardés dans le cache de coordonnées
+// Au-delà, les moins récemment utilisés sont supprimés. 0 = illimité
+func GetGeocodeCacheSize() int {
+	return getInt("GEOCODE_CACHE_SIZE", 10000, 0)
+}
+
+// GetOverridesFile retourne le chemin du fichier JSON des corrections manuelles de coordonnées
+func GetOverridesFile() string {
+	overridesFile := os.Getenv("OVERRIDES_FILE")
+	if overridesFile == "" {
+		overridesFile = "coordinates_overrides.json"
+	}
+	return overridesFile
+}
+
+// GetAdminToken retourne le jeton attendu dans l'en-tête "Authorization: Bearer <jeton>"
+// des routes /admin/. Vide par défaut : les routes d'administration sont alors désactivées
+func GetAdminToken() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
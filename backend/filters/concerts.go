@@ -0,0 +1,94 @@
+package filters
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"groupie-tracker/api"
+)
+
+// ConcertFilter décrit les critères de filtrage des concerts
+// Les champs à leur valeur zéro ne filtrent rien
+type ConcertFilter struct {
+	From      time.Time // date minimum (incluse)
+	To        time.Time // date maximum (incluse)
+	Countries []string  // pays acceptés, en code ISO ("US") ou tels qu'écrits dans l'API ("usa")
+	ArtistIDs []int     // artistes acceptés
+}
+
+// ParseConcerts lit les critères depuis les paramètres d'une requête :
+// from, to (année ou date JJ-MM-AAAA), country=us,france et artist=1,4
+func ParseConcerts(values url.Values) (ConcertFilter, error) {
+	var filter ConcertFilter
+	var err error
+
+	if filter.From, err = parseDate(values, "from", false); err != nil {
+		return ConcertFilter{}, err
+	}
+	if filter.To, err = parseDate(values, "to", true); err != nil {
+		return ConcertFilter{}, err
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.From.After(filter.To) {
+		return ConcertFilter{}, fmt.Errorf("from doit être antérieur ou égal à to")
+	}
+
+	for _, country := range splitList(values.Get("country")) {
+		filter.Countries = append(filter.Countries, strings.ToLower(country))
+	}
+
+	for _, part := range splitList(values.Get("artist")) {
+		id, err := strconv.Atoi(part)
+		if err != nil || id < 1 {
+			return ConcertFilter{}, fmt.Errorf("artist: %q n'est pas un ID d'artiste valide", part)
+		}
+		filter.ArtistIDs = append(filter.ArtistIDs, id)
+	}
+
+	return filter, nil
+}
+
+// Match indique si un concert correspond au filtre
+func (f ConcertFilter) Match(concert api.Concert) bool {
+	if !f.From.IsZero() && concert.Date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && concert.Date.After(f.To) {
+		return false
+	}
+	if len(f.ArtistIDs) > 0 && !slices.Contains(f.ArtistIDs, concert.ArtistID) {
+		return false
+	}
+	if len(f.Countries) > 0 {
+		_, country, _ := api.SplitLocation(concert.Slug)
+		if !slices.Contains(f.Countries, country) && !slices.Contains(f.Countries, strings.ToLower(concert.CountryCode)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyConcerts retourne les concerts correspondant au filtre, dans leur ordre d'origine
+func ApplyConcerts(concerts []api.Concert, f ConcertFilter) []api.Concert {
+	filtered := []api.Concert{}
+	for _, concert := range concerts {
+		if f.Match(concert) {
+			filtered = append(filtered, concert)
+		}
+	}
+	return filtered
+}
+
+// splitList découpe une liste séparée par des virgules, sans les éléments vides
+func splitList(raw string) []string {
+	var items []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
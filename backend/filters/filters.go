@@ -0,0 +1,172 @@
+package filters
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"groupie-tracker/api"
+)
+
+// Filter décrit les critères de filtrage des artistes
+// Les champs à leur valeur zéro ne filtrent rien
+type Filter struct {
+	CreatedFrom    int       // année de création minimum (incluse)
+	CreatedTo      int       // année de création maximum (incluse)
+	FirstAlbumFrom time.Time // date du premier album minimum (incluse)
+	FirstAlbumTo   time.Time // date du premier album maximum (incluse)
+	Members        []int     // nombres de membres acceptés
+	Location       string    // texte cherché dans les lieux de concerts, ex: "paris" ou "sao paulo"
+}
+
+// Parse lit les critères depuis les paramètres d'une requête :
+// created_from, created_to, first_album_from, first_album_to, members=1,2,5 et location
+// Les dates de premier album acceptent une année ("1990") ou une date complète ("14-12-1990")
+func Parse(values url.Values) (Filter, error) {
+	var filter Filter
+	var err error
+
+	if filter.CreatedFrom, err = parseYear(values, "created_from"); err != nil {
+		return Filter{}, err
+	}
+	if filter.CreatedTo, err = parseYear(values, "created_to"); err != nil {
+		return Filter{}, err
+	}
+	if filter.CreatedFrom != 0 && filter.CreatedTo != 0 && filter.CreatedFrom > filter.CreatedTo {
+		return Filter{}, fmt.Errorf("created_from (%d) doit être inférieur ou égal à created_to (%d)", filter.CreatedFrom, filter.CreatedTo)
+	}
+
+	if filter.FirstAlbumFrom, err = parseDate(values, "first_album_from", false); err != nil {
+		return Filter{}, err
+	}
+	if filter.FirstAlbumTo, err = parseDate(values, "first_album_to", true); err != nil {
+		return Filter{}, err
+	}
+	if !filter.FirstAlbumFrom.IsZero() && !filter.FirstAlbumTo.IsZero() && filter.FirstAlbumFrom.After(filter.FirstAlbumTo) {
+		return Filter{}, fmt.Errorf("first_album_from doit être antérieur ou égal à first_album_to")
+	}
+
+	if raw := strings.TrimSpace(values.Get("members")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			count, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || count < 1 {
+				return Filter{}, fmt.Errorf("members: %q n'est pas un nombre de membres valide", part)
+			}
+			filter.Members = append(filter.Members, count)
+		}
+	}
+
+	filter.Location = strings.TrimSpace(values.Get("location"))
+	if filter.Location != "" && api.Slugify(filter.Location) == "" {
+		// Sans lettre ni chiffre, le texte cherché correspondrait à tous les lieux
+		return Filter{}, fmt.Errorf("location: %q ne contient ni lettre ni chiffre", filter.Location)
+	}
+
+	return filter, nil
+}
+
+// IsEmpty indique si le filtre laisse passer tous les artistes
+func (f Filter) IsEmpty() bool {
+	return f.CreatedFrom == 0 && f.CreatedTo == 0 &&
+		f.FirstAlbumFrom.IsZero() && f.FirstAlbumTo.IsZero() &&
+		len(f.Members) == 0 && f.Location == ""
+}
+
+// Match indique si un artiste, avec ses lieux de concerts, correspond au filtre
+func (f Filter) Match(artist api.ArtistWithCustomImage, locations []string) bool {
+	if f.CreatedFrom != 0 && artist.CreationDate < f.CreatedFrom {
+		return false
+	}
+	if f.CreatedTo != 0 && artist.CreationDate > f.CreatedTo {
+		return false
+	}
+
+	if !f.FirstAlbumFrom.IsZero() || !f.FirstAlbumTo.IsZero() {
+		firstAlbum, err := api.ParseConcertDate(artist.FirstAlbum)
+		if err != nil {
+			return false
+		}
+		if !f.FirstAlbumFrom.IsZero() && firstAlbum.Before(f.FirstAlbumFrom) {
+			return false
+		}
+		if !f.FirstAlbumTo.IsZero() && firstAlbum.After(f.FirstAlbumTo) {
+			return false
+		}
+	}
+
+	if len(f.Members) > 0 && !slices.Contains(f.Members, len(artist.Members)) {
+		return false
+	}
+
+	if f.Location != "" && !matchLocation(locations, f.Location) {
+		return false
+	}
+
+	return true
+}
+
+// Apply retourne les artistes correspondant au filtre, dans leur ordre d'origine
+func Apply(artists []api.ArtistWithCustomImage, locations []api.Location, f Filter) []api.ArtistWithCustomImage {
+	locationsByID := make(map[int][]string, len(locations))
+	for _, location := range locations {
+		locationsByID[location.ID] = location.Locations
+	}
+
+	filtered := []api.ArtistWithCustomImage{}
+	for _, artist := range artists {
+		if f.Match(artist, locationsByID[artist.ID]) {
+			filtered = append(filtered, artist)
+		}
+	}
+	return filtered
+}
+
+// matchLocation indique si l'un des lieux contient le texte cherché, sans tenir compte
+// des accents, de la casse ni des séparateurs ("sao paulo" correspond à "sao_paulo-brazil")
+func matchLocation(locations []string, query string) bool {
+	needle := api.Slugify(query)
+	for _, location := range locations {
+		if strings.Contains(api.Slugify(location), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseYear lit une année dans le paramètre name (0 s'il est absent)
+func parseYear(values url.Values, name string) (int, error) {
+	raw := strings.TrimSpace(values.Get(name))
+	if raw == "" {
+		return 0, nil
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil || year < 1 || year > 9999 {
+		return 0, fmt.Errorf("%s: %q n'est pas une année valide", name, raw)
+	}
+	return year, nil
+}
+
+// parseDate lit une année ou une date "JJ-MM-AAAA" dans le paramètre name (zéro s'il est absent)
+// Pour une borne haute (endOfYear), une année seule désigne le 31 décembre
+func parseDate(values url.Values, name string, endOfYear bool) (time.Time, error) {
+	raw := strings.TrimSpace(values.Get(name))
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.Parse(api.DateLayout, raw); err == nil {
+		return date, nil
+	}
+
+	year, err := parseYear(values, name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %q n'est ni une année ni une date JJ-MM-AAAA", name, raw)
+	}
+	if endOfYear {
+		return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,132 @@
+package filters
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"années inversées", "created_from=2000&created_to=1990"},
+		{"année invalide", "created_from=abc"},
+		{"année hors limites", "created_to=10000"},
+		{"premier album inversé", "first_album_from=2010&first_album_to=2005"},
+		{"dates complètes inversées", "first_album_from=02-01-2000&first_album_to=01-01-2000"},
+		{"date mal formée", "first_album_from=2000-01-01"},
+		{"jour invalide", "first_album_to=32-01-2000"},
+		{"membres non numériques", "members=1,deux"},
+		{"membres à zéro", "members=0"},
+		{"membres négatifs", "members=-3"},
+		{"membres vides", "members=1,,2"},
+		{"lieu sans lettre ni chiffre", "location=-"},
+		{"lieu en ponctuation", "location=%20_!%20"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter, err := Parse(values); err == nil {
+				t.Errorf("Parse(%q) = %+v, erreur attendue", test.query, filter)
+			}
+		})
+	}
+}
+
+func TestParseFirstAlbumBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			name:     "années seules",
+			query:    "first_album_from=1990&first_album_to=1995",
+			wantFrom: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(1995, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "dates complètes",
+			query:    "first_album_from=14-03-1990&first_album_to=02-06-1995",
+			wantFrom: time.Date(1990, time.March, 14, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(1995, time.June, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "même année",
+			query:    "first_album_from=1990&first_album_to=1990",
+			wantFrom: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(1990, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "borne haute seule",
+			query:  "first_album_to=2001",
+			wantTo: time.Date(2001, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(test.query)
+			filter, err := Parse(values)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.query, err)
+			}
+			if !filter.FirstAlbumFrom.Equal(test.wantFrom) {
+				t.Errorf("FirstAlbumFrom = %v, attendu %v", filter.FirstAlbumFrom, test.wantFrom)
+			}
+			if !filter.FirstAlbumTo.Equal(test.wantTo) {
+				t.Errorf("FirstAlbumTo = %v, attendu %v", filter.FirstAlbumTo, test.wantTo)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	artist := api.ArtistWithCustomImage{Artist: api.Artist{
+		ID:           1,
+		Name:         "Queen",
+		Members:      []string{"Freddie Mercury", "Brian May", "Roger Taylor", "John Deacon"},
+		CreationDate: 1970,
+		FirstAlbum:   "14-12-1973",
+	}}
+	locations := []string{"london-uk", "sao_paulo-brazil", "los_angeles-usa"}
+
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"aucun filtre", "", true},
+		{"création dans l'intervalle", "created_from=1965&created_to=1975", true},
+		{"création trop récente", "created_to=1969", false},
+		{"premier album dans l'année", "first_album_from=1973&first_album_to=1973", true},
+		{"premier album après la date", "first_album_to=13-12-1973", false},
+		{"premier album le jour même", "first_album_from=14-12-1973&first_album_to=14-12-1973", true},
+		{"nombre de membres accepté", "members=2,4", true},
+		{"nombre de membres refusé", "members=1,3", false},
+		{"lieu sans accent ni séparateur", "location=S%C3%A3o%20Paulo", true},
+		{"lieu absent", "location=paris", false},
+		{"filtres combinés", "created_from=1970&first_album_to=1975&members=4&location=london", true},
+		{"filtres combinés, un seul refusé", "created_from=1970&first_album_to=1975&members=4&location=paris", false},
+		{"filtres combinés, membres refusés", "created_to=1980&members=5&location=los%20angeles", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(test.query)
+			filter, err := Parse(values)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.query, err)
+			}
+			if got := filter.Match(artist, locations); got != test.want {
+				t.Errorf("Match(%q) = %v, attendu %v", test.query, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package filters
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"groupie-tracker/api"
+)
+
+// DefaultRadiusKm est le rayon de recherche quand radius_km est absent
+const DefaultRadiusKm = 50
+
+// Nearby décrit une recherche de concerts autour d'un point
+type Nearby struct {
+	Center   api.GeoPoint
+	RadiusKm float64
+}
+
+// ParseNearby lit le centre et le rayon depuis les paramètres d'une requête :
+// lat et lon (obligatoires, en degrés décimaux) et radius_km
+func ParseNearby(values url.Values) (Nearby, error) {
+	var nearby Nearby
+	var err error
+
+	if nearby.Center.Lat, err = parseFloat(values, "lat", true); err != nil {
+		return Nearby{}, err
+	}
+	if nearby.Center.Lat < -90 || nearby.Center.Lat > 90 {
+		return Nearby{}, fmt.Errorf("lat doit être entre -90 et 90")
+	}
+	if nearby.Center.Lon, err = parseFloat(values, "lon", true); err != nil {
+		return Nearby{}, err
+	}
+	if nearby.Center.Lon < -180 || nearby.Center.Lon > 180 {
+		return Nearby{}, fmt.Errorf("lon doit être entre -180 et 180")
+	}
+
+	nearby.RadiusKm = DefaultRadiusKm
+	if strings.TrimSpace(values.Get("radius_km")) != "" {
+		if nearby.RadiusKm, err = parseFloat(values, "radius_km", true); err != nil {
+			return Nearby{}, err
+		}
+		if nearby.RadiusKm <= 0 || nearby.RadiusKm > api.MaxDistanceKm {
+			return Nearby{}, fmt.Errorf("radius_km doit être entre 0 et %.0f", api.MaxDistanceKm)
+		}
+	}
+
+	return nearby, nil
+}
+
+// ParseBBox lit une zone de la carte depuis les paramètres d'une requête :
+// south, west, north et east (obligatoires, en degrés décimaux)
+// west peut être supérieur à east quand la zone traverse l'antiméridien
+func ParseBBox(values url.Values) (api.BoundingBox, error) {
+	var box api.BoundingBox
+	var err error
+
+	if box.South, err = parseFloat(values, "south", true); err != nil {
+		return api.BoundingBox{}, err
+	}
+	if box.West, err = parseFloat(values, "west", true); err != nil {
+		return api.BoundingBox{}, err
+	}
+	if box.North, err = parseFloat(values, "north", true); err != nil {
+		return api.BoundingBox{}, err
+	}
+	if box.East, err = parseFloat(values, "east", true); err != nil {
+		return api.BoundingBox{}, err
+	}
+	if err := box.Validate(); err != nil {
+		return api.BoundingBox{}, err
+	}
+	return box, nil
+}
+
+// ParseZoom lit le niveau de zoom de la carte dans le paramètre zoom (obligatoire, de 0 à api.MaxZoom)
+func ParseZoom(values url.Values) (int, error) {
+	raw := strings.TrimSpace(values.Get("zoom"))
+	if raw == "" {
+		return 0, fmt.Errorf("zoom est obligatoire")
+	}
+	zoom, err := strconv.Atoi(raw)
+	if err != nil || zoom < 0 || zoom > api.MaxZoom {
+		return 0, fmt.Errorf("zoom: %q n'est pas un niveau de zoom entre 0 et %d", raw, api.MaxZoom)
+	}
+	return zoom, nil
+}
+
+// Distance retourne la distance en kilomètres entre le centre et un concert,
+// et si le concert est dans le rayon
+// Un concert dont le lieu n'est pas encore géocodé n'est jamais dans le rayon
+func (n Nearby) Distance(concert api.Concert) (float64, bool) {
+	if concert.Point == nil {
+		return 0, false
+	}
+	distance := api.Distance(n.Center, *concert.Point)
+	return distance, distance <= n.RadiusKm
+}
+
+// parseFloat lit un nombre décimal dans le paramètre name (0 s'il est absent et facultatif)
+func parseFloat(values url.Values, name string, required bool) (float64, error) {
+	raw := strings.TrimSpace(values.Get(name))
+	if raw == "" {
+		if required {
+			return 0, fmt.Errorf("%s est obligatoire", name)
+		}
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%s: %q n'est pas un nombre valide", name, raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+)
+
+// Les routes /admin/ sont destinées aux opérateurs (curl, scripts) et non au frontend :
+// elles n'envoient pas d'en-tête CORS
+
+// adminContext est le contexte des tâches lancées en arrière-plan par l'administration
+// Il doit être annulé à l'arrêt du serveur, voir SetAdminContext
+var adminContext = context.Background()
+
+// SetAdminContext définit le contexte des tâches d'administration en arrière-plan (re-géocodage)
+func SetAdminContext(ctx context.Context) {
+	adminContext = ctx
+}
+
+// RequireAdmin n'exécute next que si la requête porte le jeton d'administration :
+// "Authorization: Bearer <ADMIN_TOKEN>"
+func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := config.GetAdminToken()
+		given, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		// Comparaison en temps constant pour ne pas révéler le jeton par la durée de la réponse
+		if token == "" || !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "Authentification requise", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// cacheStatsResponse ajoute aux statistiques d'un cache l'âge de son entrée la plus ancienne
+type cacheStatsResponse struct {
+	api.CacheStats
+	OldestEntryAge float64 `json:"oldestEntryAgeSeconds"`
+}
+
+// newCacheStatsResponse calcule l'âge de l'entrée la plus ancienne à partir des statistiques
+func newCacheStatsResponse(stats api.CacheStats) cacheStatsResponse {
+	response := cacheStatsResponse{CacheStats: stats}
+	if stats.OldestEntry != nil {
+		response.OldestEntryAge = time.Since(*stats.OldestEntry).Seconds()
+	}
+	return response
+}
+
+// upstreamCache retourne le cache des réponses de l'API, s'il y en a un
+func upstreamCache() *api.Cache[string, []byte] {
+	switch u := upstream.(type) {
+	case *api.Client:
+		return u.Cache
+	case *api.Store:
+		if client, ok := u.Source().(*api.Client); ok {
+			return client.Cache
+		}
+	}
+	return nil
+}
+
+// Handler for the /admin/cache route
+// Statistiques du cache de coordonnées et, s'il existe, du cache des réponses de l'API
+func AdminCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	stats := map[string]cacheStatsResponse{
+		"coordinates": newCacheStatsResponse(api.GetCacheStats()),
+	}
+	if cache := upstreamCache(); cache != nil {
+		stats["upstream"] = newCacheStatsResponse(cache.Stats())
+	}
+
+	writeAdminJSON(w, http.StatusOK, stats)
+}
+
+// cacheEntrySummary est le résumé d'une entrée du cache de coordonnées dans la liste
+type cacheEntrySummary struct {
+	Location  string        `json:"location"`
+	Name      string        `json:"name,omitempty"`
+	Point     *api.GeoPoint `json:"point,omitempty"`
+	Negative  bool          `json:"negative,omitempty"`
+	StoredAt  time.Time     `json:"storedAt"`
+	ExpiresAt *time.Time    `json:"expiresAt,omitempty"`
+}
+
+// Handler for the /admin/cache/entries and /admin/cache/entries/{location} routes
+//   - GET /admin/cache/entries?q=        liste des lieux en cache (q filtre sur le début du slug)
+//   - DELETE /admin/cache/entries        vide le cache de coordonnées
+//   - GET /admin/cache/entries/{slug}    détail d'un lieu
+//   - DELETE /admin/cache/entries/{slug} supprime un lieu, re-géocodé à la prochaine demande
+func AdminCacheEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	location := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/cache/entries"), "/")
+
+	switch {
+	case location == "" && r.Method == http.MethodGet:
+		listCacheEntries(w, r)
+	case location == "" && r.Method == http.MethodDelete:
+		api.ClearCache()
+		log.Printf("Admin: cache de coordonnées vidé")
+		w.WriteHeader(http.StatusNoContent)
+	case location != "" && r.Method == http.MethodGet:
+		entry, found := api.GetCacheEntry(location)
+		if !found {
+			http.Error(w, "Lieu absent du cache", http.StatusNotFound)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, entry)
+	case location != "" && r.Method == http.MethodDelete:
+		if !api.InvalidateCache(location) {
+			http.Error(w, "Lieu absent du cache", http.StatusNotFound)
+			return
+		}
+		log.Printf("Admin: %s supprimé du cache de coordonnées", location)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// listCacheEntries renvoie le résumé des lieux en cache, triés par slug
+func listCacheEntries(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("q")
+
+	summaries := []cacheEntrySummary{}
+	for _, entry := range api.CacheEntries() {
+		if !strings.HasPrefix(entry.Key, prefix) {
+			continue
+		}
+		summary := cacheEntrySummary{
+			Location:  entry.Key,
+			Negative:  entry.Negative,
+			StoredAt:  entry.StoredAt,
+			ExpiresAt: entry.ExpiresAt,
+		}
+		if !entry.Negative {
+			summary.Name = entry.Value.Name
+			summary.Point = &api.GeoPoint{Lat: entry.Value.Lat, Lon: entry.Value.Lon}
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Location < summaries[j].Location
+	})
+
+	writeAdminJSON(w, http.StatusOK, summaries)
+}
+
+// RegeocodeStatus est l'état du dernier re-géocodage complet
+type RegeocodeStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Total      int        `json:"total"`
+	Done       int        `json:"done"`
+	Failed     []string   `json:"failed"` // lieux sans résultat ou en erreur
+	Error      string     `json:"error,omitempty"`
+}
+
+// regeocodeJob suit le re-géocodage en cours : un seul à la fois
+var regeocodeJob struct {
+	mu     sync.Mutex
+	status RegeocodeStatus
+}
+
+// Handler for the /admin/regeocode route
+//   - GET  état du dernier re-géocodage
+//   - POST lance le re-géocodage de tous les lieux (jeu de données et cache) en arrière-plan
+func AdminRegeocodeHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		regeocodeJob.mu.Lock()
+		status := regeocodeJob.status
+		status.Failed = append([]string{}, status.Failed...)
+		regeocodeJob.mu.Unlock()
+		writeAdminJSON(w, http.StatusOK, status)
+	case http.MethodPost:
+		regeocodeJob.mu.Lock()
+		if regeocodeJob.status.Running {
+			regeocodeJob.mu.Unlock()
+			http.Error(w, "Re-géocodage déjà en cours", http.StatusConflict)
+			return
+		}
+		now := time.Now()
+		regeocodeJob.status = RegeocodeStatus{Running: true, StartedAt: &now, Failed: []string{}}
+		status := regeocodeJob.status
+		regeocodeJob.mu.Unlock()
+
+		go regeocodeAll(adminContext)
+		writeAdminJSON(w, http.StatusAccepted, status)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// regeocodeAll géocode à nouveau tous les lieux connus et met à jour regeocodeJob
+// Les coordonnées en cache ne sont remplacées que par un nouveau résultat
+func regeocodeAll(ctx context.Context) {
+	locations, err := knownLocations(ctx)
+	regeocodeJob.mu.Lock()
+	regeocodeJob.status.Total = len(locations)
+	regeocodeJob.mu.Unlock()
+
+	if err == nil {
+		log.Printf("Admin: re-géocodage de %d lieux", len(locations))
+		err = api.ForEachLimit(ctx, len(locations), config.GetFetchConcurrency(), func(ctx context.Context, i int) error {
+			_, err := api.Regeocode(ctx, locations[i])
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			regeocodeJob.mu.Lock()
+			regeocodeJob.status.Done++
+			if err != nil {
+				regeocodeJob.status.Failed = append(regeocodeJob.status.Failed, locations[i])
+			}
+			regeocodeJob.mu.Unlock()
+			if err != nil && !errors.Is(err, api.ErrNoResult) {
+				log.Printf("Admin: re-géocodage de %s impossible: %v", locations[i], err)
+			}
+			return nil
+		})
+	}
+
+	now := time.Now()
+	regeocodeJob.mu.Lock()
+	regeocodeJob.status.Running = false
+	regeocodeJob.status.FinishedAt = &now
+	if err != nil {
+		regeocodeJob.status.Error = err.Error()
+	}
+	done, failed := regeocodeJob.status.Done, len(regeocodeJob.status.Failed)
+	regeocodeJob.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Admin: re-géocodage interrompu: %v", err)
+		return
+	}
+	log.Printf("Admin: re-géocodage terminé, %d lieux traités dont %d sans résultat", done, failed)
+}
+
+// knownLocations retourne, triés, les lieux du jeu de données et ceux déjà présents dans le cache
+func knownLocations(ctx context.Context) ([]string, error) {
+	allLocations, err := upstream.FetchAllLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unique := make(map[string]bool)
+	for _, location := range allLocations {
+		for _, slug := range location.Locations {
+			unique[slug] = true
+		}
+	}
+	for _, entry := range api.CacheEntries() {
+		unique[entry.Key] = true
+	}
+
+	locations := make([]string, 0, len(unique))
+	for slug := range unique {
+		locations = append(locations, slug)
+	}
+	sort.Strings(locations)
+	return locations, nil
+}
+
+// Handler for the /admin/refresh route
+// POST recharge immédiatement le jeu de données depuis l'API, en ignorant le cache des réponses
+func AdminRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	store, ok := upstream.(*api.Store)
+	if !ok {
+		http.Error(w, "Aucun jeu de données en mémoire à rafraîchir", http.StatusConflict)
+		return
+	}
+	if cache := upstreamCache(); cache != nil {
+		cache.Clear()
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
+	defer cancel()
+	if err := store.Refresh(ctx); err != nil {
+		log.Printf("Admin: rafraîchissement du jeu de données impossible: %v", err)
+		http.Error(w, "Rafraîchissement impossible: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	dataset, _ := store.Snapshot()
+	log.Printf("Admin: jeu de données rafraîchi")
+	writeAdminJSON(w, http.StatusOK, map[string]any{
+		"fetchedAt": dataset.FetchedAt,
+		"artists":   len(dataset.Artists),
+	})
+}
+
+// Handler for the /admin/overrides and /admin/overrides/{location} routes
+//   - GET /admin/overrides             liste des corrections manuelles de coordonnées
+//   - GET /admin/overrides/{slug}      correction d'un lieu
+//   - PUT /admin/overrides/{slug}      ajoute ou remplace la correction d'un lieu (corps JSON: api.Override)
+//   - DELETE /admin/overrides/{slug}   supprime la correction, le cache et le géocodeur reprennent la main
+func AdminOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	location := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/overrides"), "/")
+
+	switch {
+	case location == "" && r.Method == http.MethodGet:
+		writeAdminJSON(w, http.StatusOK, api.Overrides())
+	case location != "" && r.Method == http.MethodGet:
+		override, found := api.GetOverride(location)
+		if !found {
+			http.Error(w, "Aucune correction pour ce lieu", http.StatusNotFound)
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, override)
+	case location != "" && r.Method == http.MethodPut:
+		var override api.Override
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&override); err != nil {
+			http.Error(w, "Correction invalide: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Lieu et coordonnées sont vérifiés avant l'enregistrement : une erreur de SetOverride
+		// ne peut alors venir que de l'écriture du fichier
+		if _, _, err := api.SplitLocation(location); err != nil {
+			http.Error(w, "Lieu invalide: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := override.Validate(); err != nil {
+			http.Error(w, "Correction invalide: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := api.SetOverride(location, override); err != nil {
+			log.Printf("Admin: enregistrement de la correction de %s impossible: %v", location, err)
+			http.Error(w, "Enregistrement de la correction impossible", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Admin: coordonnées de %s corrigées (%v, %v)", location, override.Lat, override.Lon)
+		writeAdminJSON(w, http.StatusOK, override)
+	case location != "" && r.Method == http.MethodDelete:
+		found, err := api.DeleteOverride(location)
+		if err != nil {
+			log.Printf("Admin: suppression de la correction de %s impossible: %v", location, err)
+			http.Error(w, "Suppression de la correction impossible", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, "Aucune correction pour ce lieu", http.StatusNotFound)
+			return
+		}
+		log.Printf("Admin: correction de %s supprimée", location)
+		w.WriteHeader(http.StatusNoContent)
+	case location == "":
+		methodNotAllowed(w, http.MethodGet)
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// Handler for the /admin/geocode-report route
+// Liste les lieux du cache dont le pays géocodé ne correspond pas au pays du slug,
+// candidats à une correction manuelle
+func AdminGeocodeReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeAdminJSON(w, http.StatusOK, api.CheckCountries())
+}
+
+// methodNotAllowed répond 405 avec la liste des méthodes acceptées
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(w, "Méthode non autorisée", http.StatusMethodNotAllowed)
+}
+
+// writeAdminJSON envoie value en JSON avec le statut donné
+func writeAdminJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -6,17 +6,35 @@ import (
 	"strconv"
 	"strings"
 
-	"groupie-tracker/api"
 	"groupie-tracker/config"
+	"groupie-tracker/filters"
 )
 
 // Handler for the /artists route
+// Accepte des filtres en paramètres : created_from, created_to, first_album_from,
+// first_album_to, members=1,2,5 et location
 func ArtistsHandler(w http.ResponseWriter, r *http.Request) {
-	artists, err := api.FetchArtists()
+	filter, err := filters.Parse(r.URL.Query())
+	if err != nil {
+		w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	artists, err := upstream.FetchArtists(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
 		return
 	}
+
+	if !filter.IsEmpty() {
+		locations, err := upstream.FetchAllLocations(r.Context())
+		if err != nil {
+			http.Error(w, "Erreur lors de la récupération des lieux", http.StatusInternalServerError)
+			return
+		}
+		artists = filters.Apply(artists, locations, filter)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
 	w.WriteHeader(http.StatusOK)
@@ -24,6 +42,7 @@ func ArtistsHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // Handler for /artists/{id} route
+// Ainsi que /artists/{id}/tour, la tournée de l'artiste, et /artists/{id}/concerts.ics, ses concerts en calendrier
 func ArtistConcertsHandler(w http.ResponseWriter, r *http.Request) {
 	// Extraire l'ID de l'artiste de l'URL
 	pathParts := strings.Split(r.URL.Path, "/")
@@ -38,10 +57,22 @@ func ArtistConcertsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(pathParts) > 3 && pathParts[3] != "" {
+		switch pathParts[3] {
+		case "tour":
+			serveArtistTour(w, r, artistID)
+		case "concerts.ics":
+			serveArtistCalendar(w, r, artistID)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
 	// Récupérer les concerts de l'artiste
-	concerts, err := api.FetchArtistConcerts(artistID)
+	concerts, err := upstream.FetchArtistConcerts(r.Context(), artistID)
 	if err != nil {
-		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		writeUpstreamError(w, err)
 		return
 	}
 
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+	"groupie-tracker/filters"
+)
+
+// Handler for the /concerts route
+// Accepte des filtres en paramètres : from, to, country et artist
+func ConcertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	filter, err := filters.ParseConcerts(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	concerts, err := fetchConcerts(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(filters.ApplyConcerts(concerts, filter))
+}
+
+// NearbyConcert est un concert trouvé autour d'un point, avec sa distance au centre
+type NearbyConcert struct {
+	api.Concert
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// NearbyArtist est un artiste ayant joué autour d'un point
+type NearbyArtist struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	DistanceKm float64 `json:"distanceKm"` // distance de son concert le plus proche
+	Concerts   int     `json:"concerts"`   // nombre de ses concerts dans le rayon
+}
+
+// NearbyResponse est la réponse de /concerts/nearby
+type NearbyResponse struct {
+	Center   api.GeoPoint    `json:"center"`
+	RadiusKm float64         `json:"radiusKm"`
+	Concerts []NearbyConcert `json:"concerts"` // du plus proche au plus lointain
+	Artists  []NearbyArtist  `json:"artists"`  // du plus proche au plus lointain
+}
+
+// Handler for the /concerts/nearby?lat=&lon=&radius_km= route
+// Concerts et artistes dans un rayon autour d'un point, triés par distance
+// Seuls les lieux déjà présents dans le cache de géocodage sont pris en compte : la recherche
+// ne déclenche aucun appel à Nominatim. Accepte aussi les filtres de /concerts (from, to, country, artist)
+func NearbyConcertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	nearby, err := filters.ParseNearby(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := filters.ParseConcerts(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	concerts, err := fetchConcerts(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		return
+	}
+
+	response := NearbyResponse{
+		Center:   nearby.Center,
+		RadiusKm: nearby.RadiusKm,
+		Concerts: []NearbyConcert{},
+		Artists:  []NearbyArtist{},
+	}
+	artistIndex := make(map[int]int)
+	for _, concert := range filters.ApplyConcerts(concerts, filter) {
+		distance, inside := nearby.Distance(concert)
+		if !inside {
+			continue
+		}
+		distance = api.RoundKm(distance)
+		response.Concerts = append(response.Concerts, NearbyConcert{Concert: concert, DistanceKm: distance})
+
+		i, found := artistIndex[concert.ArtistID]
+		if !found {
+			i = len(response.Artists)
+			artistIndex[concert.ArtistID] = i
+			response.Artists = append(response.Artists, NearbyArtist{ID: concert.ArtistID, Name: concert.ArtistName, DistanceKm: distance})
+		}
+		response.Artists[i].Concerts++
+		response.Artists[i].DistanceKm = math.Min(response.Artists[i].DistanceKm, distance)
+	}
+
+	// Tri stable : à distance égale, les concerts restent dans l'ordre chronologique
+	sort.SliceStable(response.Concerts, func(i, j int) bool {
+		return response.Concerts[i].DistanceKm < response.Concerts[j].DistanceKm
+	})
+	sort.SliceStable(response.Artists, func(i, j int) bool {
+		if response.Artists[i].DistanceKm != response.Artists[j].DistanceKm {
+			return response.Artists[i].DistanceKm < response.Artists[j].DistanceKm
+		}
+		return response.Artists[i].Name < response.Artists[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// fetchConcerts construit la liste chronologique des concerts de tous les artistes
+func fetchConcerts(ctx context.Context) ([]api.Concert, error) {
+	artists, err := upstream.FetchArtists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := upstream.FetchAllRelations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.BuildConcerts(artists, relations), nil
+}
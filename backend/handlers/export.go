@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+	"groupie-tracker/filters"
+)
+
+// utf8BOM fait reconnaître l'UTF-8 à Excel, qui sinon affiche mal les accents ("BeyoncÃ©")
+const utf8BOM = "\uFEFF"
+
+// csvFlushRows est le nombre de lignes écrites entre deux envois au client
+const csvFlushRows = 100
+
+// Handler for the /export/artists.csv route
+// Une ligne par artiste, ou une ligne par membre avec ?by=member
+// Accepte bom=1 pour ajouter le BOM UTF-8 attendu par Excel
+func ExportArtistsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	byMember := false
+	switch by := r.URL.Query().Get("by"); by {
+	case "", "artist":
+	case "member":
+		byMember = true
+	default:
+		http.Error(w, "Paramètre by invalide (artist ou member)", http.StatusBadRequest)
+		return
+	}
+	bom, err := parseBOM(r)
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	artists, err := upstream.FetchArtists(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
+		return
+	}
+
+	header := []string{"artist_id", "artist_name", "members", "member_count", "creation_date", "first_album", "image"}
+	if byMember {
+		header = []string{"artist_id", "artist_name", "member", "creation_date", "first_album"}
+	}
+	out := startCSV(w, "artists.csv", bom, header)
+	rows := 0
+	for _, artist := range artists {
+		id := strconv.Itoa(artist.ID)
+		creationDate := strconv.Itoa(artist.CreationDate)
+		name := csvText(artist.Name)
+		firstAlbum := csvText(isoDate(artist.FirstAlbum))
+
+		if byMember {
+			for _, member := range artist.Members {
+				out.Write([]string{id, name, csvText(member), creationDate, firstAlbum})
+				rows = flushCSV(w, out, rows+1)
+			}
+			continue
+		}
+		out.Write([]string{
+			id, name, csvText(strings.Join(artist.Members, "; ")), strconv.Itoa(len(artist.Members)),
+			creationDate, firstAlbum, csvText(artist.Image),
+		})
+		rows = flushCSV(w, out, rows+1)
+	}
+	out.Flush()
+}
+
+// Handler for the /export/concerts.csv route
+// Une ligne par concert, par ordre chronologique, avec les coordonnées des lieux déjà géocodés
+// Accepte les filtres de /concerts (from, to, country, artist) et bom=1
+func ExportConcertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	filter, err := filters.ParseConcerts(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	bom, err := parseBOM(r)
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	concerts, err := fetchConcerts(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		return
+	}
+
+	out := startCSV(w, "concerts.csv", bom, []string{
+		"artist_id", "artist_name", "date", "city", "region", "country", "country_code", "slug", "lat", "lon",
+	})
+	rows := 0
+	for _, concert := range concerts {
+		if !filter.Match(concert) {
+			continue
+		}
+		var lat, lon string
+		if concert.Point != nil {
+			lat, lon = api.FormatCoordinate(concert.Point.Lat), api.FormatCoordinate(concert.Point.Lon)
+		}
+		out.Write([]string{
+			strconv.Itoa(concert.ArtistID), csvText(concert.ArtistName), concert.Date.Format("2006-01-02"),
+			csvText(concert.City), csvText(concert.Region), csvText(concert.Country), concert.CountryCode,
+			csvText(concert.Slug), lat, lon,
+		})
+		rows = flushCSV(w, out, rows+1)
+	}
+	out.Flush()
+}
+
+// parseBOM lit le paramètre bom (absent = pas de BOM)
+func parseBOM(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("bom")
+	if raw == "" {
+		return false, nil
+	}
+	bom, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("bom: %q n'est ni true ni false", raw)
+	}
+	return bom, nil
+}
+
+// startCSV envoie les en-têtes HTTP d'un fichier CSV à télécharger et sa ligne d'en-tête
+// Les lignes sont ensuite écrites au fur et à mesure, sans construire tout le fichier en mémoire
+func startCSV(w http.ResponseWriter, filename string, bom bool, header []string) *csv.Writer {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	if bom {
+		w.Write([]byte(utf8BOM))
+	}
+
+	out := csv.NewWriter(w)
+	// Fins de ligne CRLF, comme le demande la RFC 4180 et comme les écrit Excel
+	out.UseCRLF = true
+	out.Write(header)
+	return out
+}
+
+// flushCSV envoie au client les lignes en attente toutes les csvFlushRows lignes
+// Retourne le nombre de lignes écrites, à repasser à l'appel suivant
+func flushCSV(w http.ResponseWriter, out *csv.Writer, rows int) int {
+	if rows%csvFlushRows == 0 {
+		out.Flush()
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	return rows
+}
+
+// csvText neutralise un texte venant du jeu de données avant de l'écrire dans un CSV :
+// un tableur exécuterait comme une formule une cellule commençant par =, +, - ou @,
+// elle est donc préfixée d'une apostrophe ("=1+1" devient "'=1+1")
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// isoDate convertit une date du jeu de données ("14-12-1973") au format ISO 8601 ("1973-12-14"),
+// que les tableurs reconnaissent comme une date
+// Une date illisible est gardée telle quelle
+func isoDate(raw string) string {
+	date, err := api.ParseConcertDate(raw)
+	if err != nil {
+		return raw
+	}
+	return date.Format("2006-01-02")
+}
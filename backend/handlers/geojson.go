@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+)
+
+// writeArtistGeoJSON envoie les lieux de concerts d'un artiste en FeatureCollection GeoJSON
+// Utilisé par LocationsHandler pour /locations/{id}.geojson
+func writeArtistGeoJSON(ctx context.Context, w http.ResponseWriter, artistID int, relation api.Relation, coordinates map[string]api.GeocodeResponse) {
+	var artistNames []string
+	artists, err := upstream.FetchArtists(ctx)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
+		return
+	}
+	for _, artist := range artists {
+		if artist.ID == artistID {
+			artistNames = []string{artist.Name}
+			break
+		}
+	}
+
+	features := make([]api.Feature, 0, len(coordinates))
+	for location, coordinates := range coordinates {
+		features = append(features, api.NewLocationFeature(location, coordinates, relation.DatesLocations[location], artistNames))
+	}
+
+	writeGeoJSON(w, api.NewFeatureCollection(features))
+}
+
+// Handler for the /all-locations.geojson route
+// Tous les lieux de concerts déjà géocodés en FeatureCollection GeoJSON, avec les artistes et les dates de chaque lieu
+func AllLocationsGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
+	defer cancel()
+
+	artists, err := upstream.FetchArtists(ctx)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
+		return
+	}
+	relations, err := upstream.FetchAllRelations(ctx)
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des lieux", http.StatusInternalServerError)
+		return
+	}
+
+	artistNames := make(map[int]string, len(artists))
+	for _, artist := range artists {
+		artistNames[artist.ID] = artist.Name
+	}
+
+	// Regrouper par lieu les dates et les artistes de toutes les relations
+	dates := make(map[string][]string)
+	artistsByLocation := make(map[string][]string)
+	for _, relation := range relations {
+		for location, locationDates := range relation.DatesLocations {
+			location = strings.TrimSpace(location)
+			if location == "" {
+				continue
+			}
+			dates[location] = append(dates[location], locationDates...)
+			if name, found := artistNames[relation.ID]; found {
+				artistsByLocation[location] = append(artistsByLocation[location], name)
+			}
+		}
+	}
+
+	// Seuls les lieux déjà géocodés sont inclus : géocoder tout le dataset ici dépasserait
+	// le délai de la requête (1 appel/s à Nominatim), le cache est rempli par warm-cache
+	features := make([]api.Feature, 0, len(dates))
+	for location := range dates {
+		coordinates, found := api.GetFromCache(location)
+		if !found {
+			continue
+		}
+		features = append(features, api.NewLocationFeature(location, coordinates, dates[location], artistsByLocation[location]))
+	}
+
+	writeGeoJSON(w, api.NewFeatureCollection(features))
+}
+
+// writeGeoJSON envoie une FeatureCollection avec le type MIME GeoJSON
+func writeGeoJSON(w http.ResponseWriter, collection api.FeatureCollection) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collection)
+}
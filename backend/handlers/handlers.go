@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"groupie-tracker/api"
+)
+
+// upstream est la source de données utilisée par tous les handlers
+// Par défaut l'API Groupie Tracker configurée par les variables d'environnement
+var upstream api.Upstream = api.NewClientFromConfig()
+
+// SetUpstream remplace la source de données des handlers
+// Utile pour brancher un miroir, une copie de test ou un serveur httptest
+func SetUpstream(u api.Upstream) {
+	upstream = u
+}
+
+// writeUpstreamError répond à une erreur de récupération des données ou du géocodage :
+// 404 pour un artiste inconnu, 504 si le délai de la requête est dépassé, 500 sinon
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, api.ErrArtistNotFound) || errors.Is(err, api.ErrUpstreamNotFound):
+		http.Error(w, "Artiste non trouvé", http.StatusNotFound)
+	case errors.Is(err, context.DeadlineExceeded):
+		http.Error(w, "Délai dépassé lors de la récupération des données", http.StatusGatewayTimeout)
+	default:
+		http.Error(w, "Erreur lors de la récupération des données", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"groupie-tracker/api"
+)
+
+func TestArtistConcertsHandlerStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/dates/1":
+			w.Write([]byte(`{"id":1,"dates":["23-08-2019"]}`))
+		case "/api/locations/1":
+			w.Write([]byte(`{"id":1,"locations":["paris-france"]}`))
+		case "/api/dates/3", "/api/locations/3":
+			http.Error(w, "panne", http.StatusInternalServerError)
+		case "/api/dates/4", "/api/locations/4":
+			// Plus lent que le délai de la requête
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	previous := upstream
+	SetUpstream(api.NewClient(server.URL+"/api", server.Client(), ""))
+	defer SetUpstream(previous)
+
+	tests := []struct {
+		name       string
+		path       string
+		timeout    time.Duration
+		wantStatus int
+	}{
+		{"artiste existant", "/artists/1", 0, http.StatusOK},
+		{"artiste inconnu", "/artists/2", 0, http.StatusNotFound},
+		{"erreur de l'API", "/artists/3", 0, http.StatusInternalServerError},
+		{"délai dépassé", "/artists/4", 50 * time.Millisecond, http.StatusGatewayTimeout},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.timeout > 0 {
+				ctx, cancel := context.WithTimeout(request.Context(), test.timeout)
+				defer cancel()
+				request = request.WithContext(ctx)
+			}
+			recorder := httptest.NewRecorder()
+
+			ArtistConcertsHandler(recorder, request)
+
+			if recorder.Code != test.wantStatus {
+				t.Errorf("GET %s: statut %d, attendu %d (%s)", test.path, recorder.Code, test.wantStatus, recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+)
+
+// serveArtistCalendar répond à /artists/{id}/concerts.ics
+// Les concerts de l'artiste en calendrier iCalendar, à importer ou à suivre par abonnement
+func serveArtistCalendar(w http.ResponseWriter, r *http.Request, artistID int) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
+	defer cancel()
+
+	artist, concerts, err := fetchArtistConcerts(ctx, artistID)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s-concerts.ics"`, api.Slugify(artist.Name)))
+	w.WriteHeader(http.StatusOK)
+	api.WriteCalendar(w, artist, concerts, time.Now())
+}
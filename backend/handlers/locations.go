@@ -1,63 +1,129 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"groupie-tracker/api"
 	"groupie-tracker/config"
 )
 
+// Location est un lieu de concerts dans l'API v1 : coordonnées en texte, comme Nominatim
 type Location struct {
 	Name        string    `json:"name"`
 	Lat         string    `json:"lat"`
 	Lon         string    `json:"lon"`
-	BoundingBox [4]string `json:"boundingbox"`
+	BoundingBox [4]string `json:"boundingbox"` // [sud, nord, ouest, est]
 	Type        string    `json:"type"`
 	Dates       []string  `json:"dates"`
 }
 
+// LocationV2 est un lieu de concerts dans l'API v2 : coordonnées numériques
+type LocationV2 struct {
+	Name        string          `json:"name"`
+	Lat         float64         `json:"lat"`
+	Lon         float64         `json:"lon"`
+	BoundingBox api.BoundingBox `json:"boundingBox"`
+	Type        string          `json:"type"`
+	CountryCode string          `json:"countryCode,omitempty"`
+	Dates       []string        `json:"dates"`
+}
+
 // Handler for the /locations/ route
 func LocationsHandler(w http.ResponseWriter, r *http.Request) {
+	serveLocations(w, r, strings.TrimPrefix(r.URL.Path, "/locations/"), 1)
+}
+
+// Handler for the /v2/locations/ route
+// Mêmes lieux que /locations/, avec des coordonnées numériques
+func LocationsV2Handler(w http.ResponseWriter, r *http.Request) {
+	serveLocations(w, r, strings.TrimPrefix(r.URL.Path, "/v2/locations/"), 2)
+}
+
+// serveLocations renvoie les lieux de concerts géocodés de l'artiste désigné par path ("{id}" ou
+// "{id}.geojson"), au format de la version de l'API demandée
+func serveLocations(w http.ResponseWriter, r *http.Request, path string, version int) {
 	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
 
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) < 3 {
+	idPart := strings.Split(path, "/")[0]
+	if idPart == "" {
 		http.Error(w, "ID d'artiste manquant", http.StatusBadRequest)
 		return
 	}
 
-	artistID, err := strconv.Atoi(pathParts[2])
+	// /locations/{id}.geojson renvoie les mêmes lieux au format GeoJSON
+	idPart, geoJSON := strings.CutSuffix(idPart, ".geojson")
+	artistID, err := strconv.Atoi(idPart)
 	if err != nil {
 		http.Error(w, "ID d'artiste invalide", http.StatusBadRequest)
 		return
 	}
 
-	relation, err := api.FetchLocations(artistID)
+	// La récupération et le géocodage de tous les lieux doivent tenir dans le délai de la requête,
+	// et s'arrêtent si le client abandonne
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
+	defer cancel()
+
+	relation, err := upstream.FetchLocations(ctx, artistID)
 	if err != nil {
-		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		writeUpstreamError(w, err)
 		return
 	}
 
-	response := make(map[string]Location)
+	locations := make([]string, 0, len(relation.DatesLocations))
+	for location := range relation.DatesLocations {
+		locations = append(locations, location)
+	}
 
-	// range on the datesLocations keys
-	for location, dates := range relation.DatesLocations {
-		coordinates, err := api.GetCoordinates(location)
-		if err != nil {
-			http.Error(w, "Erreur lors de la récupération des coordonnées", http.StatusInternalServerError)
-			return
+	coordinates, err := geocodeLocations(ctx, locations)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	if geoJSON {
+		writeArtistGeoJSON(ctx, w, artistID, relation, coordinates)
+		return
+	}
+
+	var response any
+	if version == 2 {
+		locations := make(map[string]LocationV2, len(coordinates))
+		for location, coordinates := range coordinates {
+			locations[location] = LocationV2{
+				Name:        coordinates.Name,
+				Lat:         coordinates.Lat,
+				Lon:         coordinates.Lon,
+				BoundingBox: coordinates.BoundingBox,
+				Type:        coordinates.Type,
+				CountryCode: coordinates.CountryCode,
+				Dates:       relation.DatesLocations[location],
+			}
 		}
-		response[location] = Location{
-			Name:        coordinates[0].Name,
-			Lat:         coordinates[0].Lat,
-			Lon:         coordinates[0].Lon,
-			BoundingBox: coordinates[0].BoundingBox,
-			Type:        coordinates[0].Type,
-			Dates:       dates,
+		response = locations
+	} else {
+		locations := make(map[string]Location, len(coordinates))
+		for location, coordinates := range coordinates {
+			box := coordinates.BoundingBox
+			locations[location] = Location{
+				Name: coordinates.Name,
+				Lat:  api.FormatCoordinate(coordinates.Lat),
+				Lon:  api.FormatCoordinate(coordinates.Lon),
+				BoundingBox: [4]string{
+					api.FormatCoordinate(box.South), api.FormatCoordinate(box.North),
+					api.FormatCoordinate(box.West), api.FormatCoordinate(box.East),
+				},
+				Type:  coordinates.Type,
+				Dates: relation.DatesLocations[location],
+			}
 		}
+		response = locations
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -66,10 +132,35 @@ func LocationsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// geocodeLocations géocode des lieux en parallèle, avec un nombre limité d'appels simultanés
+// Les lieux introuvables sont absents du résultat
+func geocodeLocations(ctx context.Context, locations []string) (map[string]api.GeocodeResponse, error) {
+	var mu sync.Mutex
+	result := make(map[string]api.GeocodeResponse, len(locations))
+	err := api.ForEachLimit(ctx, len(locations), config.GetFetchConcurrency(), func(ctx context.Context, i int) error {
+		location := locations[i]
+		coordinates, err := api.GetCoordinates(ctx, location)
+		if errors.Is(err, api.ErrNoResult) || errors.Is(err, api.ErrGeocoderUnavailable) {
+			// Lieu introuvable (ou hors-ligne et absent du gazetteer) : on l'ignore plutôt que d'échouer
+			log.Printf("Lieu ignoré: %v", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		result[location] = coordinates
+		return nil
+	})
+	return result, err
+}
+
 // Handler pour récupérer tous les lieux disponibles
 func AllLocationsHandler(w http.ResponseWriter, r *http.Request) {
 	// Récupérer tous les artistes pour collecter leurs lieux
-	artists, err := api.FetchArtists()
+	artists, err := upstream.FetchArtists(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
 		return
@@ -77,7 +168,7 @@ func AllLocationsHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Collecter tous les lieux uniques
 	allLocations := make(map[string][]string) // lieu -> [artistes]
-	locations, err := api.FetchAllLocations()
+	locations, err := upstream.FetchAllLocations(r.Context())
 	if err != nil {
 		http.Error(w, "Erreur lors de la récupération des lieux", http.StatusInternalServerError)
 		return
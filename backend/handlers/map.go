@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+	"groupie-tracker/filters"
+)
+
+// concertIndex est l'index spatial des concerts de tous les artistes, pour les requêtes de la carte
+// Reconstruit à la demande quand le jeu de données est rafraîchi ou que des coordonnées changent
+var concertIndex struct {
+	mu         sync.Mutex
+	index      *api.SpatialIndex
+	generation uint64 // api.CoordinatesGeneration() au moment de la construction
+}
+
+// ResetConcertIndex oublie l'index spatial, qui sera reconstruit à la prochaine requête
+// A brancher sur api.Store.OnRefresh
+func ResetConcertIndex(*api.Dataset) {
+	concertIndex.mu.Lock()
+	concertIndex.index = nil
+	concertIndex.mu.Unlock()
+}
+
+// currentConcertIndex retourne l'index spatial à jour, en le reconstruisant si besoin
+func currentConcertIndex(ctx context.Context) (*api.SpatialIndex, error) {
+	// Lu avant de construire la liste des concerts : un lieu géocodé pendant la construction
+	// rendra l'index périmé au lieu d'être oublié
+	generation := api.CoordinatesGeneration()
+
+	concertIndex.mu.Lock()
+	defer concertIndex.mu.Unlock()
+
+	if concertIndex.index != nil && concertIndex.generation == generation {
+		return concertIndex.index, nil
+	}
+
+	concerts, err := fetchConcerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	concertIndex.index = api.NewSpatialIndex(concerts)
+	concertIndex.generation = generation
+	return concertIndex.index, nil
+}
+
+// Handler for the /concerts/in-bbox?south=&west=&north=&east= route
+// Concerts de tous les artistes dont le lieu est dans la zone visible de la carte, par ordre chronologique
+// Accepte aussi les filtres de /concerts (from, to, country, artist)
+// Comme /concerts/nearby, seuls les lieux déjà géocodés sont pris en compte
+func InBBoxConcertsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	box, err := filters.ParseBBox(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := filters.ParseConcerts(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	index, err := currentConcertIndex(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		return
+	}
+
+	concerts := []api.Concert{}
+	for _, location := range index.Within(box) {
+		concerts = append(concerts, matchingConcerts(location, filter)...)
+	}
+	api.SortConcerts(concerts)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(concerts)
+}
+
+// ClustersResponse est la réponse de /concerts/clusters
+type ClustersResponse struct {
+	Zoom     int           `json:"zoom"`
+	Count    int           `json:"count"` // nombre total de concerts dans la zone
+	Clusters []api.Cluster `json:"clusters"`
+}
+
+// Handler for the /concerts/clusters?zoom=&south=&west=&north=&east= route
+// Concerts de la zone visible regroupés en marqueurs selon le niveau de zoom : la carte
+// peut afficher tous les concerts du monde sans recevoir chaque point
+// Accepte aussi les filtres de /concerts (from, to, country, artist)
+func ConcertClustersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	zoom, err := filters.ParseZoom(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	box, err := filters.ParseBBox(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Paramètre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter, err := filters.ParseConcerts(r.URL.Query())
+	if err != nil {
+		http.Error(w, "Filtre invalide: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	index, err := currentConcertIndex(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des concerts", http.StatusInternalServerError)
+		return
+	}
+
+	response := ClustersResponse{Zoom: zoom}
+	var locations []api.IndexedLocation
+	for _, location := range index.Within(box) {
+		concerts := matchingConcerts(location, filter)
+		if len(concerts) == 0 {
+			continue
+		}
+		response.Count += len(concerts)
+		locations = append(locations, api.IndexedLocation{Slug: location.Slug, Point: location.Point, Concerts: concerts})
+	}
+	response.Clusters = api.ClusterLocations(locations, zoom)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// matchingConcerts retourne les concerts d'un lieu de l'index correspondant au filtre
+func matchingConcerts(location *api.IndexedLocation, filter filters.ConcertFilter) []api.Concert {
+	var concerts []api.Concert
+	for _, concert := range location.Between(filter.From, filter.To) {
+		if filter.Match(concert) {
+			concerts = append(concerts, concert)
+		}
+	}
+	return concerts
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+	"groupie-tracker/search"
+)
+
+const (
+	// defaultSearchLimit est le nombre de résultats renvoyés par défaut, comme dans la barre de recherche
+	defaultSearchLimit = 10
+	// maxSearchLimit borne le paramètre limit
+	maxSearchLimit = 100
+)
+
+// searchIndex est l'index de recherche du jeu de données courant
+// Reconstruit par RebuildSearchIndex à chaque rafraîchissement du store
+var searchIndex struct {
+	mu    sync.RWMutex
+	index *search.Index
+}
+
+// RebuildSearchIndex reconstruit l'index de recherche à partir d'un nouveau jeu de données
+// A brancher sur api.Store.OnRefresh
+func RebuildSearchIndex(dataset *api.Dataset) {
+	index := search.NewIndex(dataset.Artists, dataset.Locations)
+
+	searchIndex.mu.Lock()
+	searchIndex.index = index
+	searchIndex.mu.Unlock()
+}
+
+// currentSearchIndex retourne l'index à jour, ou en construit un à la volée
+// si aucun store ne l'alimente (upstream branché directement sur l'API)
+func currentSearchIndex(ctx context.Context) (*search.Index, error) {
+	searchIndex.mu.RLock()
+	index := searchIndex.index
+	searchIndex.mu.RUnlock()
+	if index != nil {
+		return index, nil
+	}
+
+	artists, err := upstream.FetchArtists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := upstream.FetchAllLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return search.NewIndex(artists, locations), nil
+}
+
+// Handler for the /search?q= route
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	query := r.URL.Query().Get("q")
+
+	limit := defaultSearchLimit
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		parsed, err := strconv.Atoi(rawLimit)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			http.Error(w, "Paramètre limit invalide (entre 1 et 100)", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	index, err := currentSearchIndex(r.Context())
+	if err != nil {
+		http.Error(w, "Erreur lors de la récupération des artistes", http.StatusInternalServerError)
+		return
+	}
+
+	results := index.Search(query, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(results)
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+)
+
+// maxTourGapDays borne le paramètre gap_days
+const maxTourGapDays = 3650
+
+// ArtistTour est la réponse de /artists/{id}/tour
+type ArtistTour struct {
+	ArtistID        int        `json:"artistId"`
+	ArtistName      string     `json:"artistName"`
+	GapDays         int        `json:"gapDays"` // jours sans concert séparant deux tournées
+	Concerts        int        `json:"concerts"`
+	TotalDistanceKm float64    `json:"totalDistanceKm"` // somme des distances de toutes les tournées
+	Tours           []api.Tour `json:"tours"`
+}
+
+// serveArtistTour répond à /artists/{id}/tour?gap_days=
+// Les concerts de l'artiste dans l'ordre chronologique, regroupés en tournées, avec la distance
+// parcourue entre chaque concert
+func serveArtistTour(w http.ResponseWriter, r *http.Request, artistID int) {
+	w.Header().Set("Access-Control-Allow-Origin", config.GetFrontendURL())
+
+	gapDays := api.DefaultTourGapDays
+	if rawGap := r.URL.Query().Get("gap_days"); rawGap != "" {
+		parsed, err := strconv.Atoi(rawGap)
+		if err != nil || parsed < 1 || parsed > maxTourGapDays {
+			http.Error(w, fmt.Sprintf("Paramètre gap_days invalide (entre 1 et %d)", maxTourGapDays), http.StatusBadRequest)
+			return
+		}
+		gapDays = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), config.GetRequestTimeout())
+	defer cancel()
+
+	artist, concerts, err := fetchArtistConcerts(ctx, artistID)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	tour := ArtistTour{
+		ArtistID:   artist.ID,
+		ArtistName: artist.Name,
+		GapDays:    gapDays,
+		Concerts:   len(concerts),
+		Tours:      api.BuildTours(concerts, gapDays),
+	}
+	for _, t := range tour.Tours {
+		tour.TotalDistanceKm += t.DistanceKm
+	}
+	tour.TotalDistanceKm = api.RoundKm(tour.TotalDistanceKm)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tour)
+}
+
+// fetchArtistConcerts retourne un artiste et ses concerts, triés par ordre chronologique
+// Les lieux de l'artiste sont géocodés au passage, comme pour /locations/{id}, pour que
+// chaque concert ait ses coordonnées
+func fetchArtistConcerts(ctx context.Context, artistID int) (api.Artist, []api.Concert, error) {
+	artists, err := upstream.FetchArtists(ctx)
+	if err != nil {
+		return api.Artist{}, nil, err
+	}
+	var artist *api.ArtistWithCustomImage
+	for i := range artists {
+		if artists[i].ID == artistID {
+			artist = &artists[i]
+			break
+		}
+	}
+	if artist == nil {
+		return api.Artist{}, nil, fmt.Errorf("%w: %d", api.ErrArtistNotFound, artistID)
+	}
+
+	relation, err := upstream.FetchLocations(ctx, artistID)
+	if err != nil {
+		return api.Artist{}, nil, err
+	}
+
+	locations := make([]string, 0, len(relation.DatesLocations))
+	for location := range relation.DatesLocations {
+		locations = append(locations, location)
+	}
+	if _, err := geocodeLocations(ctx, locations); err != nil {
+		return api.Artist{}, nil, err
+	}
+
+	concerts := api.BuildConcerts([]api.ArtistWithCustomImage{*artist}, []api.Relation{relation})
+	return artist.Artist, concerts, nil
+}
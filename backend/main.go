@@ -1,25 +1,148 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"groupie-tracker/api"
 	"groupie-tracker/config"
 	"groupie-tracker/handlers"
 )
 
+const usage = `Usage:
+  groupie-tracker                  démarre le serveur API
+  groupie-tracker snapshot <file>  exporte tout le jeu de données de l'API dans un fichier de snapshot
+  groupie-tracker warm-cache [-refresh] [-prune]
+                                   géocode tous les lieux du jeu de données dans coordinates_cache.json`
+
 func main() {
+	// ctx est annulé à la réception de SIGINT/SIGTERM : les appels en cours sont abandonnés
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Écrire sur disque les coordonnées en attente avant de quitter
+	defer api.FlushCache()
+
+	if len(os.Args) < 2 {
+		serve(ctx)
+		return
+	}
+
+	switch os.Args[1] {
+	case "serve":
+		serve(ctx)
+	case "snapshot":
+		if len(os.Args) != 3 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		dumpSnapshot(ctx, os.Args[2])
+	case "warm-cache":
+		// warmCache enregistre lui-même le cache : os.Exit n'exécute pas les fonctions différées
+		os.Exit(warmCache(ctx, os.Args[2:]))
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// dumpSnapshot télécharge tout le jeu de données depuis l'API et l'écrit dans path
+func dumpSnapshot(ctx context.Context, path string) {
+	client := api.NewClientFromConfig()
+	dataset, err := client.FetchDataset(ctx)
+	if err != nil {
+		log.Fatalf("Récupération du jeu de données impossible: %v", err)
+	}
+	if err := api.WriteSnapshot(path, dataset, client.BaseURL); err != nil {
+		log.Fatalf("Écriture du snapshot impossible: %v", err)
+	}
+	log.Printf("Snapshot écrit dans %s (%d artistes)", path, len(dataset.Artists))
+}
+
+// newGeocoder construit la chaîne de géocodage : gazetteer local s'il est configuré,
+// puis Nominatim pour les lieux inconnus (api.Offline en mode hors-ligne)
+func newGeocoder(offline bool) api.Geocoder {
+	var chain api.Chain
+	if gazetteerFile := config.GetGazetteerFile(); gazetteerFile != "" {
+		gazetteer, err := api.LoadGazetteer(gazetteerFile)
+		if err != nil {
+			log.Fatalf("Chargement du gazetteer impossible: %v", err)
+		}
+		log.Printf("Gazetteer chargé avec %d lieux", gazetteer.Len())
+		chain = append(chain, gazetteer)
+	}
+	if offline {
+		chain = append(chain, api.Offline{})
+	} else {
+		chain = append(chain, api.NewNominatimClientFromConfig())
+	}
+	return chain
+}
+
+func serve(ctx context.Context) {
 	// Configuration via variables d'environnement
 	port := config.GetPort()
 	frontendURL := config.GetFrontendURL()
 
+	// Charger le jeu de données une fois au démarrage puis le rafraîchir en arrière-plan
+	// Avec SNAPSHOT_FILE, tout est servi depuis le fichier, sans aucun accès réseau
+	var store *api.Store
+	if snapshotFile := config.GetSnapshotFile(); snapshotFile != "" {
+		log.Printf("Mode hors-ligne: données servies depuis %s", snapshotFile)
+		store = api.NewStore(api.FileSource{Path: snapshotFile}, 0)
+		if err := store.Refresh(ctx); err != nil {
+			log.Fatalf("Chargement du snapshot impossible: %v", err)
+		}
+	} else {
+		store = api.NewStore(api.NewClientFromConfig(), config.GetDatasetRefreshInterval())
+		if err := store.Refresh(ctx); err != nil {
+			log.Printf("Chargement initial du jeu de données impossible, nouvel essai au prochain rafraîchissement: %v", err)
+		}
+		go store.Run(ctx)
+	}
+	handlers.SetUpstream(store)
+	store.OnRefresh(handlers.RebuildSearchIndex)
+	store.OnRefresh(handlers.ResetConcertIndex)
+	api.SetGeocoder(newGeocoder(config.GetSnapshotFile() != ""))
+
 	// Route API REST
 	http.HandleFunc("/artists", handlers.ArtistsHandler)
 	http.HandleFunc("/artists/", handlers.ArtistConcertsHandler)
 	http.HandleFunc("/images/", handlers.ImagesHandler)
 	http.HandleFunc("/locations/", handlers.LocationsHandler)
+	http.HandleFunc("/v2/locations/", handlers.LocationsV2Handler)
 	http.HandleFunc("/all-locations", handlers.AllLocationsHandler)
+	http.HandleFunc("/all-locations.geojson", handlers.AllLocationsGeoJSONHandler)
+	http.HandleFunc("/search", handlers.SearchHandler)
+	http.HandleFunc("/concerts", handlers.ConcertsHandler)
+	http.HandleFunc("/concerts/nearby", handlers.NearbyConcertsHandler)
+	http.HandleFunc("/concerts/in-bbox", handlers.InBBoxConcertsHandler)
+	http.HandleFunc("/concerts/clusters", handlers.ConcertClustersHandler)
+	http.HandleFunc("/export/artists.csv", handlers.ExportArtistsHandler)
+	http.HandleFunc("/export/concerts.csv", handlers.ExportConcertsHandler)
+
+	// Routes d'administration, uniquement si un jeton est configuré
+	if config.GetAdminToken() != "" {
+		handlers.SetAdminContext(ctx)
+		http.HandleFunc("/admin/cache", handlers.RequireAdmin(handlers.AdminCacheHandler))
+		http.HandleFunc("/admin/cache/entries", handlers.RequireAdmin(handlers.AdminCacheEntriesHandler))
+		http.HandleFunc("/admin/cache/entries/", handlers.RequireAdmin(handlers.AdminCacheEntriesHandler))
+		http.HandleFunc("/admin/regeocode", handlers.RequireAdmin(handlers.AdminRegeocodeHandler))
+		http.HandleFunc("/admin/refresh", handlers.RequireAdmin(handlers.AdminRefreshHandler))
+		http.HandleFunc("/admin/overrides", handlers.RequireAdmin(handlers.AdminOverridesHandler))
+		http.HandleFunc("/admin/overrides/", handlers.RequireAdmin(handlers.AdminOverridesHandler))
+		http.HandleFunc("/admin/geocode-report", handlers.RequireAdmin(handlers.AdminGeocodeReportHandler))
+	} else {
+		log.Printf("ADMIN_TOKEN non défini: routes d'administration désactivées")
+	}
 
 	// Message d'accueil sur /
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +152,21 @@ func main() {
 	})
 
 	// Start server
-	serverPort := ":" + port
+	// Le contexte de chaque requête dérive de ctx : un arrêt du serveur annule les appels en cours
+	server := &http.Server{
+		Addr:        ":" + port,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		log.Printf("Arrêt du serveur...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
 	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(serverPort, nil))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
 }
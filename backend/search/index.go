@@ -0,0 +1,281 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"groupie-tracker/api"
+)
+
+// Pénalités retirées au score de base d'un document selon la qualité de la correspondance
+const (
+	penaltyPrefix = 5  // le terme commence par le mot cherché (autocomplétion)
+	penaltyInfix  = 15 // le terme contient le mot cherché
+	penaltyTypo   = 40 // par faute de frappe (distance d'édition)
+)
+
+// document est un texte indexé : un nom d'artiste, un membre, un lieu, une date...
+type document struct {
+	result Result // résultat renvoyé si le document correspond, avec son score de base
+	folded string // texte complet sans accents ni majuscules
+}
+
+// Index est un index inversé des artistes et lieux, tolérant aux fautes de frappe
+// et insensible aux accents et à la casse ("Beyonce" trouve "Beyoncé", "sao paulo" trouve "sao_paulo-brazil")
+// Un Index est immuable : il faut en construire un nouveau quand le jeu de données change
+type Index struct {
+	documents []document
+	postings  map[string][]int // terme -> documents qui le contiennent
+	terms     []string         // termes triés, pour la recherche par préfixe
+}
+
+// NewIndex construit l'index des artistes et de leurs lieux de concerts
+func NewIndex(artists []api.ArtistWithCustomImage, locations []api.Location) *Index {
+	index := &Index{postings: make(map[string][]int)}
+
+	for _, artist := range artists {
+		index.add(artist.Name, Result{
+			Type:        TypeArtist,
+			Artist:      artist,
+			DisplayText: artist.Name,
+			SubText:     "Artiste/Groupe",
+			Score:       1000,
+		})
+		for _, member := range artist.Members {
+			index.add(member, Result{
+				Type:        TypeMember,
+				Artist:      artist,
+				DisplayText: member,
+				SubText:     "Membre de " + artist.Name,
+				Score:       900,
+			})
+		}
+		index.add(strconv.Itoa(artist.CreationDate), Result{
+			Type:        TypeCreationDate,
+			Artist:      artist,
+			DisplayText: artist.Name,
+			SubText:     "Créé en " + strconv.Itoa(artist.CreationDate),
+			Score:       700,
+		})
+		if artist.FirstAlbum != "" {
+			index.add(artist.FirstAlbum, Result{
+				Type:        TypeFirstAlbum,
+				Artist:      artist,
+				DisplayText: artist.Name,
+				SubText:     "Premier album: " + artist.FirstAlbum,
+				Score:       600,
+			})
+		}
+	}
+
+	artistsByID := make(map[int]api.ArtistWithCustomImage, len(artists))
+	for _, artist := range artists {
+		artistsByID[artist.ID] = artist
+	}
+	for _, locationObject := range locations {
+		artist, found := artistsByID[locationObject.ID]
+		if !found {
+			continue
+		}
+		for _, location := range uniqueLocations(locationObject.Locations) {
+			index.add(location, Result{
+				Type:        TypeLocation,
+				Artist:      artist,
+				DisplayText: "Concert de " + artist.Name,
+				SubText:     "Lieu: " + location,
+				Score:       800,
+			})
+		}
+	}
+
+	index.terms = make([]string, 0, len(index.postings))
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+
+	return index
+}
+
+// add indexe un texte et le résultat associé
+func (idx *Index) add(text string, result Result) {
+	id := len(idx.documents)
+	terms := tokenize(text)
+	idx.documents = append(idx.documents, document{result: result, folded: strings.Join(terms, " ")})
+
+	seen := make(map[string]bool)
+	for _, term := range terms {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		idx.postings[term] = append(idx.postings[term], id)
+	}
+}
+
+// Search cherche query dans l'index
+// Chaque mot de la requête doit correspondre à un terme du document, exactement, en préfixe,
+// à l'intérieur du terme, ou à quelques fautes de frappe près
+// Les résultats sont triés par pertinence puis par type, et limités à limit (si limit > 0)
+func (idx *Index) Search(query string, limit int) []Result {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return []Result{}
+	}
+
+	// Pour chaque document, cumul des pénalités des mots qui y correspondent
+	penalties := make(map[int]int)
+	for i, word := range words {
+		matches := idx.match(word)
+		for id, penalty := range matches {
+			if i == 0 {
+				penalties[id] = penalty
+			} else if current, found := penalties[id]; found {
+				penalties[id] = current + penalty
+			}
+		}
+		// Un document doit correspondre à tous les mots
+		for id := range penalties {
+			if _, found := matches[id]; !found {
+				delete(penalties, id)
+			}
+		}
+	}
+
+	phrase := strings.Join(words, " ")
+	results := make([]Result, 0, len(penalties))
+	for id, penalty := range penalties {
+		document := idx.documents[id]
+		if strings.HasPrefix(document.folded, phrase) {
+			// Le texte commence par la requête : meilleur score possible pour ce type
+			penalty = 0
+		}
+		result := document.result
+		result.Score -= penalty
+		results = append(results, result)
+	}
+
+	// Trier par score décroissant, puis par ordre de priorité des types, puis par texte
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if typePriority[results[i].Type] != typePriority[results[j].Type] {
+			return typePriority[results[i].Type] < typePriority[results[j].Type]
+		}
+		if results[i].DisplayText != results[j].DisplayText {
+			return results[i].DisplayText < results[j].DisplayText
+		}
+		return results[i].SubText < results[j].SubText
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// match retourne les documents contenant un terme proche de word, avec la plus petite pénalité
+func (idx *Index) match(word string) map[int]int {
+	matches := make(map[int]int)
+	add := func(term string, penalty int) {
+		for _, id := range idx.postings[term] {
+			if current, found := matches[id]; !found || penalty < current {
+				matches[id] = penalty
+			}
+		}
+	}
+
+	// Correspondance exacte et préfixe : recherche dichotomique dans les termes triés
+	start := sort.SearchStrings(idx.terms, word)
+	for i := start; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], word); i++ {
+		if idx.terms[i] == word {
+			add(idx.terms[i], 0)
+		} else {
+			add(idx.terms[i], penaltyPrefix)
+		}
+	}
+
+	maxEdits := allowedEdits(word)
+	wordLength := len([]rune(word))
+	for _, term := range idx.terms {
+		if strings.HasPrefix(term, word) {
+			continue // déjà traité
+		}
+		if strings.Contains(term, word) {
+			add(term, penaltyInfix)
+			continue
+		}
+		if maxEdits == 0 {
+			continue
+		}
+
+		termRunes := []rune(term)
+		// Faute de frappe sur le mot entier, ou sur le début du terme (autocomplétion)
+		distance := editDistance([]rune(word), termRunes)
+		if len(termRunes) > wordLength {
+			if prefixDistance := editDistance([]rune(word), termRunes[:wordLength]); prefixDistance < distance {
+				distance = prefixDistance
+			}
+		}
+		if distance <= maxEdits {
+			add(term, penaltyTypo*distance)
+		}
+	}
+
+	return matches
+}
+
+// allowedEdits retourne le nombre de fautes de frappe tolérées selon la longueur du mot
+// Les nombres (années, dates) doivent correspondre exactement
+func allowedEdits(word string) int {
+	if _, err := strconv.Atoi(word); err == nil {
+		return 0
+	}
+	switch length := len([]rune(word)); {
+	case length <= 2:
+		return 0
+	case length <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// tokenize découpe un texte en mots sans accents ni majuscules
+// Les séparateurs du jeu de données ("_" et "-") sont traités comme des espaces
+func tokenize(text string) []string {
+	return strings.FieldsFunc(api.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editDistance calcule la distance de Damerau-Levenshtein (variante "optimal string alignment")
+// entre a et b : insertions, suppressions, substitutions et inversions de deux lettres voisines
+func editDistance(a, b []rune) int {
+	// previous2, previous et current sont trois lignes consécutives de la matrice de distances
+	previous2 := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(b)]
+}
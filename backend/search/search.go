@@ -0,0 +1,59 @@
+package search
+
+import (
+	"strings"
+
+	"groupie-tracker/api"
+)
+
+// ResultType est le type d'un résultat de recherche
+// Les valeurs correspondent au type SearchResultType du frontend
+type ResultType string
+
+const (
+	TypeArtist       ResultType = "artist"
+	TypeMember       ResultType = "member"
+	TypeLocation     ResultType = "location"
+	TypeCreationDate ResultType = "creation_date"
+	TypeFirstAlbum   ResultType = "first_album"
+)
+
+// typePriority départage les résultats de même score
+var typePriority = map[ResultType]int{
+	TypeArtist:       1,
+	TypeMember:       2,
+	TypeLocation:     3,
+	TypeCreationDate: 4,
+	TypeFirstAlbum:   5,
+}
+
+// Result est un résultat de recherche, au même format que SearchResult dans le frontend
+type Result struct {
+	Type        ResultType                `json:"type"`
+	Artist      api.ArtistWithCustomImage `json:"artist"`
+	DisplayText string                    `json:"displayText"`
+	SubText     string                    `json:"subText"`
+	Score       int                       `json:"score"`
+}
+
+// Search cherche query dans les noms d'artistes, les membres, les lieux de concerts,
+// les dates de création et les dates de premier album
+// Construit un index à chaque appel : pour des recherches répétées, utiliser NewIndex
+func Search(artists []api.ArtistWithCustomImage, locations []api.Location, query string, limit int) []Result {
+	return NewIndex(artists, locations).Search(query, limit)
+}
+
+// uniqueLocations retourne les lieux sans doublons, dans leur ordre d'apparition
+func uniqueLocations(locations []string) []string {
+	seen := make(map[string]bool, len(locations))
+	var unique []string
+	for _, location := range locations {
+		location = strings.TrimSpace(location)
+		if location == "" || seen[location] {
+			continue
+		}
+		seen[location] = true
+		unique = append(unique, location)
+	}
+	return unique
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"groupie-tracker/api"
+	"groupie-tracker/config"
+)
+
+// warmCache géocode tous les lieux du jeu de données et les enregistre dans coordinates_cache.json,
+// pour livrer dans l'image Docker un cache complet au lieu de le remplir au fil des requêtes
+// Le fichier est trié par lieu et sans dates : deux exécutions sur les mêmes données donnent le même fichier
+// Retourne le code de sortie du programme : 1 si des lieux n'ont pas pu être géocodés à cause d'une erreur
+func warmCache(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("warm-cache", flag.ExitOnError)
+	refresh := flags.Bool("refresh", false, "re-géocoder aussi les lieux déjà en cache")
+	prune := flags.Bool("prune", false, "supprimer du cache les lieux absents du jeu de données")
+	flags.Parse(args)
+
+	// Même source que le serveur : le snapshot en mode hors-ligne, l'API sinon
+	var upstream api.Upstream = api.NewClientFromConfig()
+	if snapshotFile := config.GetSnapshotFile(); snapshotFile != "" {
+		store := api.NewStore(api.FileSource{Path: snapshotFile}, 0)
+		if err := store.Refresh(ctx); err != nil {
+			log.Printf("Chargement du snapshot impossible: %v", err)
+			return 1
+		}
+		upstream = store
+	}
+	api.SetGeocoder(newGeocoder(false))
+
+	allLocations, err := upstream.FetchAllLocations(ctx)
+	if err != nil {
+		log.Printf("Récupération des lieux impossible: %v", err)
+		return 1
+	}
+	locations := uniqueLocations(allLocations)
+	log.Printf("%d lieux à géocoder", len(locations))
+
+	if *prune {
+		pruneCache(locations)
+	}
+
+	var cached, geocoded, noResult int
+	var failed []string
+	start := time.Now()
+	for i, location := range locations {
+		if ctx.Err() != nil {
+			break
+		}
+
+		status := "géocodé"
+		// GetCacheEntry ne compte ni succès ni échec dans les statistiques du cache
+		entry, alreadyCached := api.GetCacheEntry(location)
+		if _, overridden := api.GetOverride(location); overridden {
+			status = "corrigé à la main"
+			cached++
+		} else if alreadyCached && entry.Negative && !*refresh {
+			status = "déjà en cache, sans résultat"
+			noResult++
+		} else if alreadyCached && !*refresh {
+			status = "déjà en cache"
+			cached++
+		} else if _, err := api.Regeocode(ctx, location); errors.Is(err, api.ErrNoResult) {
+			status = "aucun résultat"
+			noResult++
+		} else if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			status = "erreur: " + err.Error()
+			failed = append(failed, location)
+		} else {
+			geocoded++
+		}
+		log.Printf("[%d/%d] %s: %s", i+1, len(locations), location, status)
+	}
+
+	// Enregistrer ce qui a été géocodé, même en cas d'interruption
+	if err := api.WriteSortedCache(); err != nil {
+		log.Printf("Écriture du cache impossible: %v", err)
+		return 1
+	}
+
+	fmt.Printf("\n%d lieux: %d géocodés, %d déjà en cache, %d sans résultat, %d en erreur (%s)\n",
+		len(locations), geocoded, cached, noResult, len(failed), time.Since(start).Round(time.Second))
+	for _, location := range failed {
+		fmt.Printf("  échec: %s\n", location)
+	}
+
+	if ctx.Err() != nil {
+		log.Printf("Interrompu, les lieux déjà géocodés ont été enregistrés")
+		return 1
+	}
+	if len(failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// uniqueLocations retourne les lieux distincts de tous les artistes, triés
+func uniqueLocations(allLocations []api.Location) []string {
+	unique := make(map[string]bool)
+	for _, location := range allLocations {
+		for _, slug := range location.Locations {
+			unique[slug] = true
+		}
+	}
+
+	locations := make([]string, 0, len(unique))
+	for slug := range unique {
+		locations = append(locations, slug)
+	}
+	sort.Strings(locations)
+	return locations
+}
+
+// pruneCache supprime du cache de coordonnées les lieux qui ne sont pas dans locations
+func pruneCache(locations []string) {
+	known := make(map[string]bool, len(locations))
+	for _, location := range locations {
+		known[location] = true
+	}
+
+	removed := 0
+	for _, entry := range api.CacheEntries() {
+		if !known[entry.Key] {
+			api.InvalidateCache(entry.Key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("%d lieux absents du jeu de données supprimés du cache", removed)
+	}
+}